@@ -2,44 +2,180 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/dimitarkovachev/eng-assist/pkg/ai"
 	"github.com/dimitarkovachev/eng-assist/pkg/config"
+	"github.com/dimitarkovachev/eng-assist/pkg/extract"
+	"github.com/dimitarkovachev/eng-assist/pkg/knowledge"
+	"github.com/dimitarkovachev/eng-assist/pkg/pipeline"
+	"github.com/dimitarkovachev/eng-assist/pkg/prompt"
+	"github.com/dimitarkovachev/eng-assist/pkg/redact"
 	"github.com/dimitarkovachev/eng-assist/pkg/state"
+	"github.com/dimitarkovachev/eng-assist/pkg/summary"
+	"github.com/dimitarkovachev/eng-assist/pkg/tools"
 	"github.com/dimitarkovachev/eng-assist/pkg/transcription"
+	"github.com/dimitarkovachev/eng-assist/pkg/trigger"
 	"github.com/dimitarkovachev/eng-assist/pkg/ui"
 )
 
+// summaryTimeout bounds writing the report of a session that is ending
+const summaryTimeout = 2 * time.Minute
+
 // Assistant manages the core application components
 type Assistant struct {
 	transcription transcription.Transcriptor
 	aiClient      ai.Tool
+	pipeline      *pipeline.Pipeline
+	extractor     *extract.Extractor
+	summarizer    *summary.Summarizer
 	ui            *ui.AssistantUI
 	logger        *log.Logger
 	appState      *state.AppState
+	cfg           *config.Config
+
+	// sessionDir is where the records of the current session are saved
+	mu         sync.Mutex
+	sessionDir string
+
+	// reports tracks reports of reset sessions still being written
+	reports sync.WaitGroup
 }
 
 // NewAssistant creates a new assistant instance
 func NewAssistant(cfg *config.Config, logger *log.Logger) (*Assistant, error) {
 	assistant := &Assistant{
-		logger:   logger,
-		appState: state.NewAppState(),
+		logger:     logger,
+		appState:   state.NewAppState(),
+		cfg:        cfg,
+		sessionDir: newSessionDir(cfg),
+	}
+
+	if err := checkPricing(cfg, logger); err != nil {
+		return nil, err
+	}
+
+	// Initialize AI client for the configured provider
+	aiClient, err := newAIClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AI client: %w", err)
+	}
+	assistant.aiClient = aiClient
+
+	// Without a key or recordings the assistant still transcribes, answers are off
+	if cfg.AIProvider == "replay" && cfg.Cassette == "" {
+		logger.Printf("No AI provider configured, answers are disabled")
+		assistant.appState.SetAIError("No AI provider configured, set ANTHROPIC_API_KEY, AI_PROVIDER or AI_CASSETTE")
+	}
+
+	// Mask personal data and secrets before anything is sent to the AI backend
+	if cfg.Redact {
+		rules := redact.DefaultRules
+		if cfg.RedactPatterns != "" {
+			custom, err := redact.LoadRules(cfg.RedactPatterns)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(append([]redact.Rule(nil), rules...), custom...)
+		}
+		assistant.aiClient = redact.NewTool(assistant.aiClient, redact.New(rules))
+	}
+
+	// Count the spend of earlier runs today toward the daily cap
+	spend, err := state.LoadSpendLog(filepath.Join(cfg.SessionsDir, "spend.json"))
+	if err != nil {
+		return nil, err
+	}
+	assistant.appState.SetSpendLog(spend)
+
+	// Stop AI requests once a spending cap is reached
+	assistant.aiClient = ai.NewBudgetedTool(
+		assistant.aiClient,
+		assistant.appState,
+		ai.Budget{Session: cfg.BudgetSession, Day: cfg.BudgetDay},
+	)
+
+	// Load persona templates, falling back to the built-in prompt
+	personas, err := prompt.Load(cfg.PromptsDir, cfg.Persona)
+	if err != nil {
+		logger.Printf("Personas unavailable, using the built-in prompt: %v", err)
 	}
 
-	// Initialize AI client with mock implementation
-	assistant.aiClient = ai.NewMockTool()
+	// Index the team's documents for grounding answers
+	var index *knowledge.Index
+	if cfg.KnowledgeDir != "" {
+		index, err = knowledge.Build(cfg.KnowledgeDir)
+		if err != nil {
+			return nil, err
+		}
+		logger.Printf("Indexed %d passages from %s", index.Len(), cfg.KnowledgeDir)
+	}
+
+	// Let answers call local tools, other requests keep the plain client
+	answerClient := assistant.aiClient
+	if cfg.ToolDepth > 0 {
+		registry := newToolRegistry(cfg, assistant.appState, index)
+		answerClient = ai.NewToolLoop(assistant.aiClient, registry, cfg.ToolDepth)
+		logger.Printf("Offering %d tools to the AI, up to %d rounds per answer", registry.Len(), cfg.ToolDepth)
+	}
+
+	// Keep requests the provider could not be reached for until it is back
+	outbox, err := pipeline.NewOutbox(filepath.Join(cfg.SessionsDir, "queue.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize pipeline feeding buffered transcript to the AI client
+	assistant.pipeline = pipeline.New(
+		answerClient,
+		assistant.appState,
+		pipeline.Options{
+			BufferTimeout:  cfg.BufferTimeout,
+			RequestTimeout: cfg.RequestTimeout,
+			ContextWindow:  contextWindow(cfg),
+			Trigger:        newTrigger(cfg, assistant.aiClient),
+			Prompts:        personas,
+			Policy:         pipeline.Policy(cfg.InFlightPolicy),
+			Knowledge:      index,
+			Passages:       cfg.KnowledgeTopK,
+			Outbox:         outbox,
+		},
+		logger,
+	)
+
+	// Extract action items and decisions into the session directory
+	assistant.extractor = extract.New(
+		assistant.aiClient,
+		assistant.appState,
+		extract.NewStore(filepath.Join(assistant.sessionDir, "actions.json")),
+		contextWindow(cfg),
+		logger,
+	)
+
+	// Summarize sessions on demand, on reset and on shutdown
+	assistant.summarizer = summary.New(assistant.aiClient, assistant.appState, contextWindow(cfg), logger)
 
 	// Initialize UI with callbacks
 	assistant.ui = ui.NewAssistantUI(
 		assistant.handlePause,
+		assistant.handleReset,
+		assistant.summarize,
+		assistant.pipeline.Escalate,
+		transcription.SDLDevices{},
 		assistant.appState,
+		personas,
+		assistant.extractor,
 	)
 
 	// Initialize transcription with callback
@@ -55,16 +191,207 @@ func NewAssistant(cfg *config.Config, logger *log.Logger) (*Assistant, error) {
 	// 	assistant.appState,
 	// )
 
-	assistant.transcription = transcription.NewWhisperHandler(
-		cfg.WhisperCppPath,
-		cfg.WhisperModelPath,
-		cfg.BufferTimeout,
-		assistant.appState,
-	)
+	// Without audio the assistant still serves the UI, history and typed text
+	device, err := transcription.SelectDevice(transcription.SDLDevices{}, cfg.AudioDevice)
+	switch {
+	case errors.Is(err, transcription.ErrNoAudio):
+		logger.Printf("Starting without audio: %v", err)
+		assistant.appState.SetAudioError(err.Error())
+		assistant.transcription = transcription.NoAudio{}
+	case err != nil:
+		return nil, fmt.Errorf("invalid AUDIO_DEVICE: %w", err)
+	default:
+		logger.Printf("Capturing audio from device #%d %q", device.Index, device.Name)
+		assistant.transcription = newTranscriptor(cfg, device, assistant.appState)
+	}
 
 	return assistant, nil
 }
 
+// newTranscriptor creates the transcription backend selected by the config
+func newTranscriptor(cfg *config.Config, device transcription.Device, appState *state.AppState) transcription.Transcriptor {
+	if cfg.Transcriber == "server" {
+		return transcription.NewWhisperServer(
+			cfg.WhisperServerURL,
+			time.Duration(cfg.ChunkSeconds*float64(time.Second)),
+			device.Source(),
+			func() (io.ReadCloser, error) { return transcription.OpenCapture(device) },
+			appState,
+		)
+	}
+
+	return transcription.NewWhisperHandler(cfg.WhisperCppPath, cfg.WhisperModelPath, device, appState)
+}
+
+// newAIClient creates the AI backend selected by the config.
+// With a small model configured, a cascade answers with it first and escalates to the main model.
+// A real backend records to the cassette when one is configured, replay only plays it back.
+// Replay without a cassette answers nothing, every request fails with ai.ErrNotRecorded.
+func newAIClient(cfg *config.Config) (ai.Tool, error) {
+	if cfg.AIProvider == "replay" {
+		cassette, err := ai.NewCassette(cfg.Cassette)
+		if err != nil {
+			return nil, err
+		}
+		return cassette, nil
+	}
+
+	client := newProvider(cfg, cfg.AIModel)
+	if cfg.AISmallModel != "" {
+		client = ai.NewCascade(newProvider(cfg, cfg.AISmallModel), client, trigger.Technical)
+	}
+
+	if cfg.Cassette == "" {
+		return client, nil
+	}
+	recorder, err := ai.NewRecorder(client, cfg.Cassette)
+	if err != nil {
+		return nil, err
+	}
+	return recorder, nil
+}
+
+// checkPricing makes sure the spending caps can be enforced for hosted models.
+// Models without a known price are counted as free, which is only right for local ones,
+// so they stop the start when a cap is set and are warned about otherwise.
+func checkPricing(cfg *config.Config, logger *log.Logger) error {
+	if cfg.AIProvider == "ollama" || cfg.AIProvider == "replay" {
+		return nil
+	}
+
+	for _, model := range []string{cfg.AIModel, cfg.AISmallModel} {
+		// Empty means the provider's default model, which is priced
+		if model == "" || ai.Priced(model) {
+			continue
+		}
+		if cfg.BudgetSession > 0 || cfg.BudgetDay > 0 {
+			return fmt.Errorf("no price known for model %q, so the spending caps cannot be enforced: check the model name or unset AI_BUDGET_SESSION_USD and AI_BUDGET_DAY_USD", model)
+		}
+		logger.Printf("Warning: no price known for model %q, its requests are counted as free", model)
+	}
+	return nil
+}
+
+// newProvider creates a client of the configured provider for the model
+func newProvider(cfg *config.Config, model string) ai.Tool {
+	switch cfg.AIProvider {
+	case "openai":
+		return ai.NewOpenAIClient(cfg.OpenAIApiKey, model, cfg.AIBaseURL)
+	case "ollama":
+		return ai.NewOllamaClient(model, cfg.AIBaseURL)
+	default:
+		return ai.NewAnthropicClient(cfg.AnthropicApiKey, model, cfg.AIBaseURL)
+	}
+}
+
+// newTrigger creates the question detector selected by the config
+func newTrigger(cfg *config.Config, tool ai.Tool) trigger.Trigger {
+	switch cfg.Trigger {
+	case "llm":
+		return trigger.NewLLM(tool)
+	case "always":
+		return trigger.Always{}
+	default:
+		return trigger.NewHeuristic()
+	}
+}
+
+// newToolRegistry returns the local tools the AI may call while answering.
+// Knowledge search and file reading are only offered when configured.
+func newToolRegistry(cfg *config.Config, appState *state.AppState, index *knowledge.Index) *tools.Registry {
+	registry := tools.NewRegistry()
+	registry.Register(tools.SearchTranscript(appState.TranscriptState))
+	registry.Register(tools.Calculate())
+	if index != nil {
+		registry.Register(tools.SearchKnowledge(index))
+	}
+	if cfg.RepoDir != "" {
+		registry.Register(tools.ReadFile(cfg.RepoDir))
+	}
+	return registry
+}
+
+// contextWindow returns the configured context window or the default for the model
+func contextWindow(cfg *config.Config) int {
+	if cfg.ContextWindow > 0 {
+		return cfg.ContextWindow
+	}
+	if cfg.AIProvider == "anthropic" && cfg.AIModel == "" {
+		return ai.ContextWindowFor("claude")
+	}
+	return ai.ContextWindowFor(cfg.AIModel)
+}
+
+// newSessionDir returns the directory the records of a session starting now are saved in
+func newSessionDir(cfg *config.Config) string {
+	return filepath.Join(cfg.SessionsDir, time.Now().Format("2006-01-02-150405"))
+}
+
+// handleReset saves the report of the session being cleared in the background
+// and starts a fresh conversation and session
+func (a *Assistant) handleReset() {
+	transcript := a.appState.TranscriptState.History()
+	segments := a.appState.TranscriptState.Segments()
+
+	a.mu.Lock()
+	dir := a.sessionDir
+	a.sessionDir = newSessionDir(a.cfg)
+	a.extractor.Reset(filepath.Join(a.sessionDir, "actions.json"))
+	a.mu.Unlock()
+
+	a.reports.Add(1)
+	go func() {
+		defer a.reports.Done()
+		a.closeSession(dir, transcript, segments)
+	}()
+
+	a.pipeline.Reset()
+}
+
+// summarize writes the report of the current session so far and saves it to the session directory
+func (a *Assistant) summarize(ctx context.Context) (*summary.Report, error) {
+	a.mu.Lock()
+	dir := a.sessionDir
+	a.mu.Unlock()
+
+	transcript := a.appState.TranscriptState.History()
+	report, err := a.summarizer.Summarize(ctx, transcript)
+	if err != nil {
+		return nil, err
+	}
+	if err := summary.Save(dir, transcript, report); err != nil {
+		return nil, err
+	}
+	if err := summary.SaveSegments(dir, a.appState.TranscriptState.Segments()); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// closeSession saves the report of a session that is ending.
+// The transcript is kept even when summarizing fails.
+func (a *Assistant) closeSession(dir string, transcript string, segments []state.Segment) {
+	if strings.TrimSpace(transcript) == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), summaryTimeout)
+	defer cancel()
+
+	report, err := a.summarizer.Summarize(ctx, transcript)
+	if err != nil {
+		a.logger.Printf("Error summarizing session, saving the transcript only: %v", err)
+	}
+	if err := summary.Save(dir, transcript, report); err != nil {
+		a.logger.Printf("Error saving session report: %v", err)
+		return
+	}
+	if err := summary.SaveSegments(dir, segments); err != nil {
+		a.logger.Printf("Error saving transcript segments: %v", err)
+	}
+	a.logger.Printf("Saved session report to %s", dir)
+}
+
 // handlePause toggles the pause state
 func (a *Assistant) handlePause() {
 	// if a.appState.IsPaused() {
@@ -81,14 +408,45 @@ func (a *Assistant) handlePause() {
 func (a *Assistant) Run(ctx context.Context) error {
 	// Start transcription
 	if err := a.transcription.Start(ctx); err != nil {
-		return err
+		a.logger.Printf("Starting without audio: %v", err)
+		a.appState.SetAudioError(err.Error())
+	}
+
+	// Start pipeline
+	go a.pipeline.Run(ctx)
+
+	// Start periodic extraction
+	if a.cfg.ExtractInterval > 0 {
+		go a.extractor.Run(ctx, time.Duration(a.cfg.ExtractInterval*float64(time.Second)))
 	}
 
 	// Start UI
-	if err := a.ui.Run(ctx); err != nil {
+	err := a.ui.Run(ctx)
+
+	// Save the report of the session that is ending and of any reset ones still being written
+	a.mu.Lock()
+	dir := a.sessionDir
+	a.mu.Unlock()
+	a.closeSession(dir, a.appState.TranscriptState.History(), a.appState.TranscriptState.Segments())
+	a.reports.Wait()
+
+	return err
+}
+
+// printDevices lists the capture devices AUDIO_DEVICE can select
+func printDevices() error {
+	devices, err := transcription.SDLDevices{}.CaptureDevices()
+	if err != nil {
 		return err
 	}
 
+	if len(devices) == 0 {
+		fmt.Println("No capture devices found")
+		return nil
+	}
+	for _, device := range devices {
+		fmt.Printf("%3d  %s\n", device.Index, device.Name)
+	}
 	return nil
 }
 
@@ -98,6 +456,14 @@ func main() {
 	debug := flag.Bool("debug", false, "Enable debug mode")
 	flag.Parse()
 
+	// The devices subcommand only lists capture devices, so it works without a config
+	if flag.Arg(0) == "devices" {
+		if err := printDevices(); err != nil {
+			log.Fatalf("Failed to list capture devices: %v", err)
+		}
+		return
+	}
+
 	// Setup logging
 	logFlags := log.LstdFlags
 	if *debug {
@@ -0,0 +1,318 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultAnthropicURL   = "https://api.anthropic.com"
+	defaultAnthropicModel = "claude-sonnet-4-5"
+	anthropicVersion      = "2023-06-01"
+	defaultMaxTokens      = 1024
+)
+
+// AnthropicClient talks to the Anthropic Messages API
+type AnthropicClient struct {
+	apiKey     string
+	model      string
+	baseURL    string
+	httpClient *http.Client
+	retry      RetryPolicy
+
+	meter
+}
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	System    any                `json:"system,omitempty"`
+	Messages  []anthropicMessage `json:"messages"`
+	Tools     []anthropicTool    `json:"tools,omitempty"`
+	Stream    bool               `json:"stream,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// anthropicMessage holds either plain string content or content blocks
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+// anthropicBlock is a content block of any type, only the fields of its type are set
+type anthropicBlock struct {
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text,omitempty"`
+	ID           string                 `json:"id,omitempty"`
+	Name         string                 `json:"name,omitempty"`
+	Input        json.RawMessage        `json:"input,omitempty"`
+	ToolUseID    string                 `json:"tool_use_id,omitempty"`
+	Content      string                 `json:"content,omitempty"`
+	IsError      bool                   `json:"is_error,omitempty"`
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+type anthropicCacheControl struct {
+	Type string `json:"type"`
+}
+
+type anthropicResponse struct {
+	Model   string           `json:"model"`
+	Usage   Usage            `json:"usage"`
+	Content []anthropicBlock `json:"content"`
+}
+
+// anthropicStreamEvent covers the fields used from all server-sent event types
+type anthropicStreamEvent struct {
+	Type         string            `json:"type"`
+	Index        int               `json:"index"`
+	Message      anthropicResponse `json:"message"`
+	ContentBlock anthropicBlock    `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+	} `json:"delta"`
+	Usage Usage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewAnthropicClient creates a new Messages API client.
+// Empty model and baseURL fall back to the defaults.
+func NewAnthropicClient(apiKey string, model string, baseURL string) *AnthropicClient {
+	if model == "" {
+		model = defaultAnthropicModel
+	}
+	if baseURL == "" {
+		baseURL = defaultAnthropicURL
+	}
+
+	return &AnthropicClient{
+		apiKey:     apiKey,
+		model:      model,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{},
+		retry:      DefaultRetryPolicy,
+	}
+}
+
+// Complete sends the request to the Messages API and returns the text reply
+func (c *AnthropicClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	resp, err := c.send(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var msg anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	response := &Response{Model: msg.Model, Usage: msg.Usage}
+	var text strings.Builder
+	for _, block := range msg.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			response.ToolCalls = append(response.ToolCalls, ToolCall{ID: block.ID, Name: block.Name, Input: block.Input})
+		}
+	}
+	response.Text = text.String()
+
+	return c.account(response), nil
+}
+
+// Stream sends the request to the Messages API and emits text deltas as they arrive
+func (c *AnthropicClient) Stream(ctx context.Context, req Request) (<-chan StreamEvent, error) {
+	resp, err := c.send(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		final, err := c.readStream(ctx, resp.Body, events)
+		if err != nil {
+			final = StreamEvent{Err: err}
+		}
+
+		select {
+		case events <- final:
+		case <-ctx.Done():
+		}
+	}()
+
+	return events, nil
+}
+
+// readStream forwards text deltas from the event stream and returns the final event
+func (c *AnthropicClient) readStream(ctx context.Context, body io.Reader, events chan<- StreamEvent) (StreamEvent, error) {
+	response := &Response{Model: c.model}
+	var text strings.Builder
+
+	// Tool call inputs arrive as JSON fragments per content block
+	calls := make(map[int]*ToolCall)
+	inputs := make(map[int]*strings.Builder)
+	var order []int
+
+	scanner := newLineScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &event); err != nil {
+			return StreamEvent{}, fmt.Errorf("failed to decode stream event: %w", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message.Model != "" {
+				response.Model = event.Message.Model
+			}
+			response.Usage = event.Message.Usage
+
+			// The input is billed even if the stream does not finish
+			partial := &Response{Model: response.Model, Usage: response.Usage}
+			partial.Cost = Cost(partial.Model, partial.Usage)
+			select {
+			case events <- StreamEvent{Partial: partial}:
+			case <-ctx.Done():
+				return StreamEvent{}, ctx.Err()
+			}
+		case "content_block_start":
+			if event.ContentBlock.Type == "tool_use" {
+				calls[event.Index] = &ToolCall{ID: event.ContentBlock.ID, Name: event.ContentBlock.Name}
+				inputs[event.Index] = &strings.Builder{}
+				order = append(order, event.Index)
+			}
+		case "content_block_delta":
+			if event.Delta.Type == "input_json_delta" {
+				if input, ok := inputs[event.Index]; ok {
+					input.WriteString(event.Delta.PartialJSON)
+				}
+				continue
+			}
+			if event.Delta.Type != "text_delta" || event.Delta.Text == "" {
+				continue
+			}
+			text.WriteString(event.Delta.Text)
+			select {
+			case events <- StreamEvent{Delta: event.Delta.Text}:
+			case <-ctx.Done():
+				return StreamEvent{}, ctx.Err()
+			}
+		case "message_delta":
+			response.Usage.OutputTokens = event.Usage.OutputTokens
+		case "error":
+			return StreamEvent{}, fmt.Errorf("anthropic stream error (%s): %s", event.Error.Type, event.Error.Message)
+		case "message_stop":
+			response.Text = text.String()
+			for _, index := range order {
+				call := calls[index]
+				call.Input = json.RawMessage(inputs[index].String())
+				if len(call.Input) == 0 {
+					call.Input = json.RawMessage("{}")
+				}
+				response.ToolCalls = append(response.ToolCalls, *call)
+			}
+			return StreamEvent{Response: c.account(response)}, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return StreamEvent{}, unreachable(ctx, fmt.Errorf("failed to read stream: %w", err))
+	}
+
+	return StreamEvent{}, unreachable(ctx, fmt.Errorf("stream ended before message_stop"))
+}
+
+// send posts the request and returns the response once the status is OK
+func (c *AnthropicClient) send(ctx context.Context, req Request, stream bool) (*http.Response, error) {
+	maxTokens := req.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = defaultMaxTokens
+	}
+
+	header := http.Header{}
+	header.Set("x-api-key", c.apiKey)
+	header.Set("anthropic-version", anthropicVersion)
+
+	body := anthropicRequest{
+		Model:     c.model,
+		MaxTokens: maxTokens,
+		Stream:    stream,
+	}
+	if req.System != "" {
+		body.System = req.System
+	}
+	for _, msg := range req.Messages {
+		body.Messages = append(body.Messages, toAnthropicMessage(msg))
+	}
+	for _, tool := range req.Tools {
+		body.Tools = append(body.Tools, anthropicTool{Name: tool.Name, Description: tool.Description, InputSchema: tool.Parameters})
+	}
+	if req.Cache {
+		addCacheBreakpoints(&body)
+	}
+
+	return postJSON(ctx, c.httpClient, c.retry, "anthropic", c.baseURL+"/v1/messages", header, body)
+}
+
+// toAnthropicMessage converts the message, using content blocks for tool calls and results
+func toAnthropicMessage(msg Message) anthropicMessage {
+	if len(msg.ToolCalls) == 0 && len(msg.ToolResults) == 0 {
+		return anthropicMessage{Role: msg.Role, Content: msg.Content}
+	}
+
+	var blocks []anthropicBlock
+	if msg.Content != "" {
+		blocks = append(blocks, anthropicBlock{Type: "text", Text: msg.Content})
+	}
+	for _, call := range msg.ToolCalls {
+		blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: call.Input})
+	}
+	for _, result := range msg.ToolResults {
+		blocks = append(blocks, anthropicBlock{Type: "tool_result", ToolUseID: result.CallID, Content: result.Content, IsError: result.IsError})
+	}
+	return anthropicMessage{Role: msg.Role, Content: blocks}
+}
+
+// addCacheBreakpoints marks the system prompt and the history before the newest message
+// as cacheable, so the growing conversation prefix is read from cache on the next request
+func addCacheBreakpoints(body *anthropicRequest) {
+	ephemeral := &anthropicCacheControl{Type: "ephemeral"}
+
+	if system, ok := body.System.(string); ok {
+		body.System = []anthropicBlock{{Type: "text", Text: system, CacheControl: ephemeral}}
+	}
+
+	if len(body.Messages) < 2 {
+		return
+	}
+	last := &body.Messages[len(body.Messages)-2]
+	switch content := last.Content.(type) {
+	case string:
+		last.Content = []anthropicBlock{{Type: "text", Text: content, CacheControl: ephemeral}}
+	case []anthropicBlock:
+		content[len(content)-1].CacheControl = ephemeral
+	}
+}
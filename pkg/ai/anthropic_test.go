@@ -0,0 +1,320 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAnthropicComplete(t *testing.T) {
+	var got anthropicRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("Expected path /v1/messages, got: %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "test-key" {
+			t.Errorf("Expected api key header, got: %s", r.Header.Get("x-api-key"))
+		}
+		if r.Header.Get("anthropic-version") != anthropicVersion {
+			t.Errorf("Expected anthropic-version header, got: %s", r.Header.Get("anthropic-version"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("Decode() error: %v", err)
+		}
+
+		w.Header().Set("content-type", "application/json")
+		w.Write([]byte(`{
+			"model": "claude-sonnet-4-5",
+			"usage": {"input_tokens": 1000, "output_tokens": 500},
+			"content": [
+				{"type": "text", "text": "Hello"},
+				{"type": "text", "text": " world"}
+			]
+		}`))
+	}))
+	defer srv.Close()
+
+	client := NewAnthropicClient("test-key", "claude-sonnet-4-5", srv.URL)
+	resp, err := client.Complete(context.Background(), Request{
+		System:   "be brief",
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("Complete() error: %v", err)
+	}
+
+	if resp.Text != "Hello world" {
+		t.Errorf("Expected 'Hello world', got: %s", resp.Text)
+	}
+	if resp.Model != "claude-sonnet-4-5" {
+		t.Errorf("Expected model 'claude-sonnet-4-5', got: %s", resp.Model)
+	}
+	if resp.Usage.InputTokens != 1000 || resp.Usage.OutputTokens != 500 {
+		t.Errorf("Expected usage to be parsed, got: %+v", resp.Usage)
+	}
+	if resp.Cost <= 0 || client.CurrentCost() != resp.Cost {
+		t.Errorf("Expected cost to be accounted, got: %f (total %f)", resp.Cost, client.CurrentCost())
+	}
+	if got.System != any("be brief") {
+		t.Errorf("Expected system prompt to be sent, got: %s", got.System)
+	}
+	if got.MaxTokens != defaultMaxTokens {
+		t.Errorf("Expected default max tokens %d, got: %d", defaultMaxTokens, got.MaxTokens)
+	}
+	if len(got.Messages) != 1 || got.Messages[0].Content != "hi" {
+		t.Errorf("Expected single user message, got: %+v", got.Messages)
+	}
+}
+
+func TestAnthropicCompleteError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"type": "error", "error": {"type": "invalid_request_error", "message": "bad prompt"}}`))
+	}))
+	defer srv.Close()
+
+	client := NewAnthropicClient("test-key", "", srv.URL)
+	_, err := client.Complete(context.Background(), Request{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	if err == nil {
+		t.Fatal("Expected error for non-200 response")
+	}
+}
+
+func TestAnthropicStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Decode() error: %v", err)
+		}
+		if !req.Stream {
+			t.Error("Expected stream to be requested")
+		}
+
+		w.Header().Set("content-type", "text/event-stream")
+		w.Write([]byte("event: message_start\n" +
+			`data: {"type": "message_start", "message": {"model": "claude-test", "content": [], "usage": {"input_tokens": 12, "output_tokens": 1}}}` + "\n\n" +
+			"event: ping\n" +
+			`data: {"type": "ping"}` + "\n\n" +
+			"event: content_block_delta\n" +
+			`data: {"type": "content_block_delta", "index": 0, "delta": {"type": "text_delta", "text": "Hel"}}` + "\n\n" +
+			"event: content_block_delta\n" +
+			`data: {"type": "content_block_delta", "index": 0, "delta": {"type": "text_delta", "text": "lo"}}` + "\n\n" +
+			"event: message_delta\n" +
+			`data: {"type": "message_delta", "delta": {"stop_reason": "end_turn"}, "usage": {"output_tokens": 7}}` + "\n\n" +
+			"event: message_stop\n" +
+			`data: {"type": "message_stop"}` + "\n\n"))
+	}))
+	defer srv.Close()
+
+	client := NewAnthropicClient("test-key", "", srv.URL)
+	events, err := client.Stream(context.Background(), Request{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("Stream() error: %v", err)
+	}
+
+	var deltas []string
+	var partial, final *Response
+	for event := range events {
+		if event.Err != nil {
+			t.Fatalf("Stream event error: %v", event.Err)
+		}
+		if event.Partial != nil {
+			partial = event.Partial
+			continue
+		}
+		if event.Response != nil {
+			final = event.Response
+			continue
+		}
+		deltas = append(deltas, event.Delta)
+	}
+
+	if partial == nil || partial.Usage.InputTokens != 12 || partial.Cost != Cost("claude-test", partial.Usage) {
+		t.Errorf("Expected the input usage before the text, got: %+v", partial)
+	}
+	if len(deltas) != 2 || deltas[0] != "Hel" || deltas[1] != "lo" {
+		t.Errorf("Expected deltas [Hel lo], got: %v", deltas)
+	}
+	if final == nil {
+		t.Fatal("Expected final response event")
+	}
+	if final.Text != "Hello" || final.Model != "claude-test" {
+		t.Errorf("Unexpected final response: %+v", final)
+	}
+	if final.Usage.InputTokens != 12 || final.Usage.OutputTokens != 7 {
+		t.Errorf("Expected streamed usage, got: %+v", final.Usage)
+	}
+}
+
+func TestAnthropicStreamError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "text/event-stream")
+		w.Write([]byte("event: error\n" +
+			`data: {"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}` + "\n\n"))
+	}))
+	defer srv.Close()
+
+	client := NewAnthropicClient("test-key", "", srv.URL)
+	events, err := client.Stream(context.Background(), Request{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("Stream() error: %v", err)
+	}
+
+	var last StreamEvent
+	for event := range events {
+		last = event
+	}
+	if last.Err == nil {
+		t.Error("Expected final event to carry the stream error")
+	}
+}
+
+func TestAnthropicStreamCutShort(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "text/event-stream")
+		w.Write([]byte("event: content_block_delta\n" +
+			`data: {"type": "content_block_delta", "index": 0, "delta": {"type": "text_delta", "text": "Hel"}}` + "\n\n"))
+	}))
+	defer srv.Close()
+
+	client := NewAnthropicClient("test-key", "", srv.URL)
+	events, err := client.Stream(context.Background(), Request{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("Stream() error: %v", err)
+	}
+
+	var last StreamEvent
+	for event := range events {
+		last = event
+	}
+	if !errors.Is(last.Err, ErrUnreachable) {
+		t.Errorf("Expected a stream cut short to be unreachable, got: %v", last.Err)
+	}
+}
+
+func TestAnthropicPromptCaching(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("Decode() error: %v", err)
+		}
+
+		w.Write([]byte(`{
+			"model": "claude-sonnet-4-5",
+			"usage": {"input_tokens": 10, "output_tokens": 5, "cache_creation_input_tokens": 0, "cache_read_input_tokens": 2000},
+			"content": [{"type": "text", "text": "ok"}]
+		}`))
+	}))
+	defer srv.Close()
+
+	client := NewAnthropicClient("test-key", "claude-sonnet-4-5", srv.URL)
+	resp, err := client.Complete(context.Background(), Request{
+		System: "persona",
+		Messages: []Message{
+			{Role: "user", Content: "old question"},
+			{Role: "assistant", Content: "old answer"},
+			{Role: "user", Content: "new question"},
+		},
+		Cache: true,
+	})
+	if err != nil {
+		t.Fatalf("Complete() error: %v", err)
+	}
+
+	system, ok := got["system"].([]any)
+	if !ok || len(system) != 1 || system[0].(map[string]any)["cache_control"] == nil {
+		t.Errorf("Expected cached system block, got: %v", got["system"])
+	}
+
+	messages := got["messages"].([]any)
+	if _, plain := messages[0].(map[string]any)["content"].(string); !plain {
+		t.Error("Expected older messages to stay plain strings")
+	}
+	breakpoint, ok := messages[1].(map[string]any)["content"].([]any)
+	if !ok || breakpoint[0].(map[string]any)["cache_control"] == nil {
+		t.Errorf("Expected cache breakpoint on the last history message, got: %v", messages[1])
+	}
+	if _, plain := messages[2].(map[string]any)["content"].(string); !plain {
+		t.Error("Expected newest message to stay outside the cached prefix")
+	}
+
+	if resp.Usage.CacheReadTokens != 2000 {
+		t.Errorf("Expected cache read tokens to be parsed, got: %+v", resp.Usage)
+	}
+	if resp.Cost != Cost("claude-sonnet-4-5", resp.Usage) {
+		t.Errorf("Expected cache tokens to be priced, got: %f", resp.Cost)
+	}
+}
+
+func TestAnthropicToolUse(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("Decode() error: %v", err)
+		}
+
+		w.Header().Set("content-type", "text/event-stream")
+		w.Write([]byte(
+			`data: {"type": "message_start", "message": {"model": "claude-test", "content": [], "usage": {"input_tokens": 20}}}` + "\n\n" +
+				`data: {"type": "content_block_start", "index": 0, "content_block": {"type": "text", "text": ""}}` + "\n\n" +
+				`data: {"type": "content_block_delta", "index": 0, "delta": {"type": "text_delta", "text": "Let me check."}}` + "\n\n" +
+				`data: {"type": "content_block_start", "index": 1, "content_block": {"type": "tool_use", "id": "toolu_1", "name": "calculate", "input": {}}}` + "\n\n" +
+				`data: {"type": "content_block_delta", "index": 1, "delta": {"type": "input_json_delta", "partial_json": "{\"expression\": "}}` + "\n\n" +
+				`data: {"type": "content_block_delta", "index": 1, "delta": {"type": "input_json_delta", "partial_json": "\"2*3\"}"}}` + "\n\n" +
+				`data: {"type": "message_delta", "delta": {"stop_reason": "tool_use"}, "usage": {"output_tokens": 9}}` + "\n\n" +
+				`data: {"type": "message_stop"}` + "\n\n"))
+	}))
+	defer srv.Close()
+
+	client := NewAnthropicClient("test-key", "", srv.URL)
+	events, err := client.Stream(context.Background(), Request{
+		Messages: []Message{
+			{Role: "user", Content: "what is 1+1?"},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "toolu_0", Name: "calculate", Input: json.RawMessage(`{"expression":"1+1"}`)}}},
+			{Role: "user", ToolResults: []ToolResult{{CallID: "toolu_0", Name: "calculate", Content: "2"}}},
+		},
+		Tools: []ToolSpec{{Name: "calculate", Description: "math", Parameters: json.RawMessage(`{"type":"object"}`)}},
+	})
+	if err != nil {
+		t.Fatalf("Stream() error: %v", err)
+	}
+
+	var final *Response
+	for event := range events {
+		if event.Err != nil {
+			t.Fatalf("Stream event error: %v", event.Err)
+		}
+		if event.Response != nil {
+			final = event.Response
+		}
+	}
+
+	if final == nil || final.Text != "Let me check." || len(final.ToolCalls) != 1 {
+		t.Fatalf("Expected a text reply with one tool call, got: %+v", final)
+	}
+	if call := final.ToolCalls[0]; call.ID != "toolu_1" || call.Name != "calculate" || string(call.Input) != `{"expression": "2*3"}` {
+		t.Errorf("Unexpected tool call: %+v", call)
+	}
+
+	tools := got["tools"].([]any)
+	if tools[0].(map[string]any)["input_schema"] == nil {
+		t.Errorf("Expected tools with an input schema, got: %v", tools)
+	}
+	messages := got["messages"].([]any)
+	use := messages[1].(map[string]any)["content"].([]any)[0].(map[string]any)
+	result := messages[2].(map[string]any)["content"].([]any)[0].(map[string]any)
+	if use["type"] != "tool_use" || result["type"] != "tool_result" || result["tool_use_id"] != "toolu_0" {
+		t.Errorf("Expected tool_use and tool_result blocks, got: %v and %v", use, result)
+	}
+}
@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrBudgetExhausted is returned instead of calling the model once a spending cap is reached
+var ErrBudgetExhausted = errors.New("ai budget exhausted")
+
+// Ledger reports the spend that counts against a budget
+type Ledger interface {
+	// SessionCost returns the spend of the current session
+	SessionCost() float64
+
+	// DayCost returns the spend on the calendar day of the given time
+	DayCost(day time.Time) float64
+}
+
+// Budget holds spending caps in USD. Zero means no cap.
+type Budget struct {
+	Session float64
+	Day     float64
+}
+
+// BudgetedTool refuses requests once the ledger reaches the budget
+type BudgetedTool struct {
+	tool   Tool
+	ledger Ledger
+	budget Budget
+}
+
+// NewBudgetedTool wraps the tool with a spending cap checked before every request
+func NewBudgetedTool(tool Tool, ledger Ledger, budget Budget) *BudgetedTool {
+	return &BudgetedTool{
+		tool:   tool,
+		ledger: ledger,
+		budget: budget,
+	}
+}
+
+// Complete sends the request unless the budget is exhausted
+func (b *BudgetedTool) Complete(ctx context.Context, req Request) (*Response, error) {
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+	return b.tool.Complete(ctx, req)
+}
+
+// Stream sends the request unless the budget is exhausted
+func (b *BudgetedTool) Stream(ctx context.Context, req Request) (<-chan StreamEvent, error) {
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+	return b.tool.Stream(ctx, req)
+}
+
+// CurrentCost returns the cost of the wrapped tool
+func (b *BudgetedTool) CurrentCost() float64 {
+	return b.tool.CurrentCost()
+}
+
+func (b *BudgetedTool) check() error {
+	if b.budget.Session > 0 && b.ledger.SessionCost() >= b.budget.Session {
+		return ErrBudgetExhausted
+	}
+	if b.budget.Day > 0 && b.ledger.DayCost(time.Now()) >= b.budget.Day {
+		return ErrBudgetExhausted
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeLedger struct {
+	session float64
+	day     float64
+}
+
+func (f *fakeLedger) SessionCost() float64 {
+	return f.session
+}
+
+func (f *fakeLedger) DayCost(day time.Time) float64 {
+	return f.day
+}
+
+// emptyCassette replays an empty reply to the empty request
+func emptyCassette(t *testing.T) Tool {
+	t.Helper()
+	cassette, err := NewCassette("")
+	if err != nil {
+		t.Fatalf("NewCassette() error: %v", err)
+	}
+	cassette.Add(Request{}, Response{Model: "replay"})
+	return cassette
+}
+
+func TestBudgetedToolAllowsUnderBudget(t *testing.T) {
+	tool := NewBudgetedTool(emptyCassette(t), &fakeLedger{session: 0.5, day: 2}, Budget{Session: 1, Day: 5})
+
+	if _, err := tool.Complete(context.Background(), Request{}); err != nil {
+		t.Fatalf("Complete() error: %v", err)
+	}
+	if _, err := tool.Stream(context.Background(), Request{}); err != nil {
+		t.Fatalf("Stream() error: %v", err)
+	}
+}
+
+func TestBudgetedToolStopsAtSessionCap(t *testing.T) {
+	tool := NewBudgetedTool(emptyCassette(t), &fakeLedger{session: 1}, Budget{Session: 1})
+
+	if _, err := tool.Complete(context.Background(), Request{}); !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("Expected ErrBudgetExhausted, got: %v", err)
+	}
+	if _, err := tool.Stream(context.Background(), Request{}); !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("Expected ErrBudgetExhausted, got: %v", err)
+	}
+}
+
+func TestBudgetedToolStopsAtDayCap(t *testing.T) {
+	tool := NewBudgetedTool(emptyCassette(t), &fakeLedger{day: 10}, Budget{Day: 5})
+
+	if _, err := tool.Complete(context.Background(), Request{}); !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("Expected ErrBudgetExhausted, got: %v", err)
+	}
+}
+
+func TestBudgetedToolZeroIsUnlimited(t *testing.T) {
+	tool := NewBudgetedTool(emptyCassette(t), &fakeLedger{session: 100, day: 100}, Budget{})
+
+	if _, err := tool.Complete(context.Background(), Request{}); err != nil {
+		t.Errorf("Expected no cap, got: %v", err)
+	}
+}
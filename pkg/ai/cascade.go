@@ -0,0 +1,190 @@
+package ai
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// Routes of a cascade, reported in Response.Route
+const (
+	RouteSmall         = "small"
+	RouteRequested     = "escalated: requested"
+	RouteTechnical     = "escalated: technical"
+	RouteLowConfidence = "escalated: low confidence"
+)
+
+// TranscriptMarker precedes the transcript in a user message that also carries other context.
+// Only the text after it is classified.
+const TranscriptMarker = "Transcript:\n"
+
+const confidenceInstruction = "\n\nEnd your reply with a last line saying how sure you are of it: " +
+	"\"Confidence: high\", \"Confidence: medium\" or \"Confidence: low\"."
+
+// confidenceRe matches the confidence line the small model ends its reply with
+var confidenceRe = regexp.MustCompile(`(?i)\n?[ \t*_]*confidence[*_]*:[ \t*_]*(high|medium|low)\b[ \t*_.]*$`)
+
+// Cascade answers with a small, cheap model and escalates to a large model when
+// the request asks for it, the question is technical or the small model is not confident.
+// The small model is asked to rate its confidence, and a reply rated low is thrown away
+// and asked again of the large model. The cost of the discarded reply is added to the answer.
+// Streamed requests wait for the whole small reply so a discarded one is never shown.
+type Cascade struct {
+	small     Tool
+	large     Tool
+	technical func(text string) bool
+}
+
+// NewCascade creates a cascade. Technical classifies the newest user text, nil never escalates on it.
+func NewCascade(small Tool, large Tool, technical func(text string) bool) *Cascade {
+	return &Cascade{
+		small:     small,
+		large:     large,
+		technical: technical,
+	}
+}
+
+// Complete answers with the model picked for the request
+func (c *Cascade) Complete(ctx context.Context, req Request) (*Response, error) {
+	if route := c.route(req); route != "" {
+		resp, err := c.large.Complete(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return routed(resp, route), nil
+	}
+
+	resp, confident, err := c.askSmall(ctx, req)
+	if err != nil || confident {
+		return resp, err
+	}
+
+	large, err := c.large.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return c.escalated(resp, large), nil
+}
+
+// Stream answers with the model picked for the request
+func (c *Cascade) Stream(ctx context.Context, req Request) (<-chan StreamEvent, error) {
+	if route := c.route(req); route != "" {
+		upstream, err := c.large.Stream(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return relay(ctx, upstream, func(resp *Response) *Response { return routed(resp, route) }), nil
+	}
+
+	small, confident, err := c.askSmall(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if confident {
+		events := make(chan StreamEvent, 2)
+		if small.Text != "" {
+			events <- StreamEvent{Delta: small.Text}
+		}
+		events <- StreamEvent{Response: small}
+		close(events)
+		return events, nil
+	}
+
+	upstream, err := c.large.Stream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return relay(ctx, upstream, func(resp *Response) *Response { return c.escalated(small, resp) }), nil
+}
+
+// CurrentCost returns the total cost of both models
+func (c *Cascade) CurrentCost() float64 {
+	return c.small.CurrentCost() + c.large.CurrentCost()
+}
+
+// route returns why the request goes straight to the large model, or empty for the small one
+func (c *Cascade) route(req Request) string {
+	if req.Escalate {
+		return RouteRequested
+	}
+	if c.technical != nil && c.technical(lastUserText(req)) {
+		return RouteTechnical
+	}
+	return ""
+}
+
+// askSmall gets the small model's reply without its confidence line
+// and reports whether it can be used as the answer
+func (c *Cascade) askSmall(ctx context.Context, req Request) (*Response, bool, error) {
+	req.System += confidenceInstruction
+
+	resp, err := c.small.Complete(ctx, req)
+	if err != nil {
+		return nil, false, err
+	}
+	resp = routed(resp, RouteSmall)
+
+	// A reply calling tools carries no rating and continues in the next round
+	if len(resp.ToolCalls) > 0 {
+		return resp, true, nil
+	}
+
+	match := confidenceRe.FindStringSubmatchIndex(resp.Text)
+	if match == nil {
+		return resp, true, nil
+	}
+
+	confidence := strings.ToLower(resp.Text[match[2]:match[3]])
+	resp.Text = strings.TrimSpace(resp.Text[:match[0]])
+	return resp, confidence != "low", nil
+}
+
+// escalated returns the large reply with the cost of the discarded small one added
+func (c *Cascade) escalated(small *Response, large *Response) *Response {
+	resp := combine(small, large)
+	resp.Route = RouteLowConfidence
+	return resp
+}
+
+func routed(resp *Response, route string) *Response {
+	routed := *resp
+	routed.Route = route
+	return &routed
+}
+
+// lastUserText returns the transcript of the newest user message with text
+func lastUserText(req Request) string {
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if msg := req.Messages[i]; msg.Role == "user" && msg.Content != "" {
+			if at := strings.LastIndex(msg.Content, TranscriptMarker); at >= 0 {
+				return msg.Content[at+len(TranscriptMarker):]
+			}
+			return msg.Content
+		}
+	}
+	return ""
+}
+
+// relay forwards the events of a stream, rewriting its final and partial responses
+func relay(ctx context.Context, upstream <-chan StreamEvent, final func(*Response) *Response) <-chan StreamEvent {
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+
+		for event := range upstream {
+			if event.Response != nil {
+				event.Response = final(event.Response)
+			}
+			if event.Partial != nil {
+				event.Partial = final(event.Partial)
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
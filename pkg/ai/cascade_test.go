@@ -0,0 +1,120 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// modelTool replies with a fixed text under its own model name
+type modelTool struct {
+	Tool
+	model    string
+	text     string
+	cost     float64
+	requests []Request
+}
+
+func (m *modelTool) Complete(ctx context.Context, req Request) (*Response, error) {
+	m.requests = append(m.requests, req)
+	return &Response{Text: m.text, Model: m.model, Cost: m.cost, Usage: Usage{InputTokens: 10}}, nil
+}
+
+func (m *modelTool) Stream(ctx context.Context, req Request) (<-chan StreamEvent, error) {
+	resp, _ := m.Complete(ctx, req)
+	events := make(chan StreamEvent, 2)
+	events <- StreamEvent{Delta: resp.Text}
+	events <- StreamEvent{Response: resp}
+	close(events)
+	return events, nil
+}
+
+func (m *modelTool) CurrentCost() float64 {
+	return m.cost * float64(len(m.requests))
+}
+
+func newCascade(smallText string) (*Cascade, *modelTool, *modelTool) {
+	small := &modelTool{model: "small", text: smallText, cost: 0.001}
+	large := &modelTool{model: "large", text: "large answer", cost: 0.01}
+	technical := func(text string) bool { return strings.Contains(text, "kubernetes") }
+	return NewCascade(small, large, technical), small, large
+}
+
+func ask(text string) Request {
+	return Request{System: "be brief", Messages: []Message{{Role: "user", Content: text}}}
+}
+
+func TestCascadeAnswersWithSmallModel(t *testing.T) {
+	cascade, small, large := newCascade("small answer\nConfidence: high")
+
+	resp, err := cascade.Complete(context.Background(), ask("what time is it?"))
+	if err != nil {
+		t.Fatalf("Complete() error: %v", err)
+	}
+
+	if resp.Text != "small answer" || resp.Model != "small" || resp.Route != RouteSmall {
+		t.Errorf("Expected the small reply without its rating, got: %+v", resp)
+	}
+	if len(large.requests) != 0 {
+		t.Errorf("Expected the large model unused, got %d requests", len(large.requests))
+	}
+	if !strings.HasSuffix(small.requests[0].System, confidenceInstruction) {
+		t.Errorf("Expected the small model asked for a rating, got: %q", small.requests[0].System)
+	}
+}
+
+func TestCascadeEscalates(t *testing.T) {
+	tests := []struct {
+		name      string
+		smallText string
+		req       Request
+		route     string
+		cost      float64
+	}{
+		{"low confidence", "a guess\n**Confidence: low**", ask("what time is it?"), RouteLowConfidence, 0.011},
+		{"technical", "unused", ask("Passages\n\n" + TranscriptMarker + "how do we scale kubernetes?"), RouteTechnical, 0.01},
+		{"requested", "unused", Request{Messages: []Message{{Role: "user", Content: "hi"}}, Escalate: true}, RouteRequested, 0.01},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cascade, _, large := newCascade(tt.smallText)
+
+			events, err := cascade.Stream(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Stream() error: %v", err)
+			}
+
+			var text strings.Builder
+			var final *Response
+			for event := range events {
+				text.WriteString(event.Delta)
+				if event.Response != nil {
+					final = event.Response
+				}
+			}
+
+			if text.String() != "large answer" || final == nil || final.Model != "large" || final.Route != tt.route {
+				t.Fatalf("Expected the large answer routed %q, got %q and %+v", tt.route, text.String(), final)
+			}
+			if final.Cost < tt.cost-1e-9 || final.Cost > tt.cost+1e-9 {
+				t.Errorf("Expected cost %f, got %f", tt.cost, final.Cost)
+			}
+			if strings.Contains(large.requests[0].System, confidenceInstruction) {
+				t.Error("Expected the large model not asked for a rating")
+			}
+		})
+	}
+}
+
+func TestCascadeTechnicalIgnoresPassages(t *testing.T) {
+	cascade, _, large := newCascade("fine\nConfidence: medium")
+
+	resp, err := cascade.Complete(context.Background(), ask("kubernetes runbook\n\n"+TranscriptMarker+"who is presenting?"))
+	if err != nil {
+		t.Fatalf("Complete() error: %v", err)
+	}
+	if resp.Route != RouteSmall || len(large.requests) != 0 {
+		t.Errorf("Expected only the transcript classified, got route %q", resp.Route)
+	}
+}
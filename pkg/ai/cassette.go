@@ -0,0 +1,276 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ErrNotRecorded is returned when a replayed request has no recorded response
+var ErrNotRecorded = errors.New("no recorded response for request")
+
+var (
+	whitespace = regexp.MustCompile(`\s+`)
+
+	// datePattern matches the dates rendered into persona prompts, which change every day
+	datePattern = regexp.MustCompile(`\b(?:Monday|Tuesday|Wednesday|Thursday|Friday|Saturday|Sunday), \d{1,2} ` +
+		`(?:January|February|March|April|May|June|July|August|September|October|November|December) \d{4}\b|\b\d{4}-\d{2}-\d{2}\b`)
+)
+
+// Interaction is a recorded request and the response it got
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type cassetteFile struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Cassette records request and response pairs of a tool to a JSON file and replays them offline.
+// Requests are matched on their normalized prompt. Repeated prompts replay their responses in
+// recorded order, and the last one is repeated once they run out. Replayed responses keep their
+// recorded usage but cost nothing, since no request is sent. While recording every request goes
+// to the tool, when replaying unmatched requests fail with ErrNotRecorded.
+type Cassette struct {
+	tool Tool
+	path string
+
+	mu           sync.Mutex
+	interactions []Interaction
+	index        map[string][]int
+	played       map[string]int
+
+	meter
+}
+
+// NewCassette creates a cassette that replays the recordings in path.
+// An empty path gives an empty cassette, which recordings can be added to with Add.
+func NewCassette(path string) (*Cassette, error) {
+	c := &Cassette{
+		path:   path,
+		index:  make(map[string][]int),
+		played: make(map[string]int),
+	}
+	if path == "" {
+		return c, nil
+	}
+
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewRecorder creates a cassette that sends every request to the tool and adds each
+// interaction to the recordings in path. Delete the file to record a session from scratch.
+// The file is created on the first recording if it does not exist.
+func NewRecorder(tool Tool, path string) (*Cassette, error) {
+	c := &Cassette{
+		tool:   tool,
+		path:   path,
+		index:  make(map[string][]int),
+		played: make(map[string]int),
+	}
+
+	if err := c.load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NormalizePrompt returns the text requests are matched on, including the tools offered and
+// whether the large model was asked for. Whitespace is collapsed and dates are masked,
+// so recordings keep matching on other days.
+func NormalizePrompt(req Request) string {
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "system: %s\n", req.System)
+	if req.Escalate {
+		prompt.WriteString("escalate\n")
+	}
+	if len(req.Tools) > 0 {
+		names := make([]string, len(req.Tools))
+		for i, tool := range req.Tools {
+			names[i] = tool.Name
+		}
+		fmt.Fprintf(&prompt, "tools: %s\n", strings.Join(names, ", "))
+	}
+	for _, msg := range req.Messages {
+		fmt.Fprintf(&prompt, "%s: %s\n", msg.Role, msg.Content)
+		for _, call := range msg.ToolCalls {
+			fmt.Fprintf(&prompt, "call %s: %s\n", call.Name, call.Input)
+		}
+		for _, result := range msg.ToolResults {
+			fmt.Fprintf(&prompt, "result %s: %s\n", result.Name, result.Content)
+		}
+	}
+
+	normalized := whitespace.ReplaceAllString(strings.TrimSpace(prompt.String()), " ")
+	return datePattern.ReplaceAllString(normalized, "<date>")
+}
+
+// Add records an interaction in memory
+func (c *Cassette) Add(req Request, resp Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.add(Interaction{Request: req, Response: resp})
+}
+
+func (c *Cassette) add(interaction Interaction) {
+	key := NormalizePrompt(interaction.Request)
+	c.index[key] = append(c.index[key], len(c.interactions))
+	c.interactions = append(c.interactions, interaction)
+}
+
+// Complete replays the recorded reply, or records the tool's reply when recording
+func (c *Cassette) Complete(ctx context.Context, req Request) (*Response, error) {
+	if c.tool == nil {
+		if resp, ok := c.replay(req); ok {
+			return resp, nil
+		}
+		return nil, c.miss(req)
+	}
+
+	resp, err := c.tool.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.record(req, *resp); err != nil {
+		return nil, err
+	}
+
+	return c.charge(resp), nil
+}
+
+// Stream replays the recorded reply as a single delta, or records the tool's streamed reply when recording
+func (c *Cassette) Stream(ctx context.Context, req Request) (<-chan StreamEvent, error) {
+	if c.tool == nil {
+		resp, ok := c.replay(req)
+		if !ok {
+			return nil, c.miss(req)
+		}
+		events := make(chan StreamEvent, 2)
+		if resp.Text != "" {
+			events <- StreamEvent{Delta: resp.Text}
+		}
+		events <- StreamEvent{Response: resp}
+		close(events)
+		return events, nil
+	}
+
+	upstream, err := c.tool.Stream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+
+		for event := range upstream {
+			if event.Response != nil {
+				if err := c.record(req, *event.Response); err != nil {
+					event = StreamEvent{Err: err}
+				} else {
+					c.charge(event.Response)
+				}
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// Interactions returns the recorded interactions in order
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Interaction(nil), c.interactions...)
+}
+
+// replay returns a copy of the next recorded response for the request, without its cost
+func (c *Cassette) replay(req Request) (*Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := NormalizePrompt(req)
+	recorded := c.index[key]
+	if len(recorded) == 0 {
+		return nil, false
+	}
+
+	n := min(c.played[key], len(recorded)-1)
+	c.played[key]++
+
+	resp := c.interactions[recorded[n]].Response
+	resp.Cost = 0
+	return &resp, true
+}
+
+func (c *Cassette) miss(req Request) error {
+	prompt := NormalizePrompt(req)
+	if len(prompt) > 200 {
+		prompt = "..." + prompt[len(prompt)-200:]
+	}
+	return fmt.Errorf("%w: %q", ErrNotRecorded, prompt)
+}
+
+// record adds the interaction and saves the cassette
+func (c *Cassette) record(req Request, resp Response) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.add(Interaction{Request: req, Response: resp})
+	return c.save()
+}
+
+func (c *Cassette) load() error {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var file cassetteFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to decode cassette %s: %w", c.path, err)
+	}
+
+	for _, interaction := range file.Interactions {
+		c.add(interaction)
+	}
+	return nil
+}
+
+// save writes the cassette through a temporary file so an interrupted write keeps the old recordings
+func (c *Cassette) save() error {
+	if c.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(cassetteFile{Interactions: c.interactions}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return os.Rename(tmp, c.path)
+}
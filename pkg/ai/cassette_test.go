@@ -0,0 +1,146 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// countingTool answers every request with the same text and counts the calls
+type countingTool struct {
+	text  string
+	calls int
+}
+
+func (f *countingTool) Complete(ctx context.Context, req Request) (*Response, error) {
+	f.calls++
+	return &Response{Text: f.text, Model: "claude-sonnet-4-5", Usage: Usage{InputTokens: 100, OutputTokens: 10}, Cost: 0.25}, nil
+}
+
+func (f *countingTool) Stream(ctx context.Context, req Request) (<-chan StreamEvent, error) {
+	resp, _ := f.Complete(ctx, req)
+	events := make(chan StreamEvent, 2)
+	events <- StreamEvent{Delta: resp.Text}
+	events <- StreamEvent{Response: resp}
+	close(events)
+	return events, nil
+}
+
+func (f *countingTool) CurrentCost() float64 {
+	return 0
+}
+
+func question(text string) Request {
+	return Request{System: "system", Messages: []Message{{Role: "user", Content: text}}}
+}
+
+func TestNormalizePrompt(t *testing.T) {
+	a := Request{System: "Today is Monday, 2 January 2006.\n\nHelp.", Messages: []Message{{Role: "user", Content: "  how do we\tdeploy? "}}}
+	b := Request{System: "Today is Friday, 17 October 2026. Help.", Messages: []Message{{Role: "user", Content: "how do we deploy?"}}}
+
+	if NormalizePrompt(a) != NormalizePrompt(b) {
+		t.Errorf("Expected prompts to match:\n%s\n%s", NormalizePrompt(a), NormalizePrompt(b))
+	}
+	if NormalizePrompt(a) == NormalizePrompt(question("how do we deploy?")) {
+		t.Error("Expected different system prompts not to match")
+	}
+
+	escalated := b
+	escalated.Escalate = true
+	if NormalizePrompt(escalated) == NormalizePrompt(b) {
+		t.Error("Expected an escalated request not to match the plain one")
+	}
+	withTools := b
+	withTools.Tools = []ToolSpec{{Name: "read_file"}}
+	if NormalizePrompt(withTools) == NormalizePrompt(b) {
+		t.Error("Expected a request offering tools not to match the plain one")
+	}
+}
+
+func TestCassetteReplays(t *testing.T) {
+	cassette, err := NewCassette("")
+	if err != nil {
+		t.Fatalf("NewCassette() error: %v", err)
+	}
+	cassette.Add(question("first?"), Response{Text: "one", Model: "m", Cost: 0.5})
+	cassette.Add(question("first?"), Response{Text: "two", Model: "m", Cost: 0.5})
+
+	for _, want := range []string{"one", "two", "two"} {
+		resp, err := cassette.Complete(context.Background(), question("first?"))
+		if err != nil {
+			t.Fatalf("Complete() error: %v", err)
+		}
+		if resp.Text != want {
+			t.Errorf("Expected %q, got: %q", want, resp.Text)
+		}
+	}
+
+	if cassette.CurrentCost() != 0 {
+		t.Errorf("Expected replays to cost nothing, got: %f", cassette.CurrentCost())
+	}
+
+	if _, err := cassette.Complete(context.Background(), question("other?")); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("Expected ErrNotRecorded, got: %v", err)
+	}
+	if _, err := cassette.Stream(context.Background(), question("other?")); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("Expected ErrNotRecorded from Stream, got: %v", err)
+	}
+}
+
+func TestCassetteRecordsAndReplaysFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "session.json")
+	tool := &countingTool{text: "recorded"}
+
+	recorder, err := NewRecorder(tool, path)
+	if err != nil {
+		t.Fatalf("NewRecorder() error: %v", err)
+	}
+
+	events, err := recorder.Stream(context.Background(), question("streamed?"))
+	if err != nil {
+		t.Fatalf("Stream() error: %v", err)
+	}
+	var text string
+	for event := range events {
+		text += event.Delta
+	}
+	if text != "recorded" {
+		t.Errorf("Expected the tool's reply, got: %q", text)
+	}
+
+	if _, err := recorder.Complete(context.Background(), question("completed?")); err != nil {
+		t.Fatalf("Complete() error: %v", err)
+	}
+	if _, err := recorder.Complete(context.Background(), question("completed?")); err != nil {
+		t.Fatalf("Complete() error: %v", err)
+	}
+	if tool.calls != 3 {
+		t.Errorf("Expected a repeated prompt to be sent again while recording, got %d calls", tool.calls)
+	}
+	if recorder.CurrentCost() != 0.75 {
+		t.Errorf("Expected every sent request to be charged, got: %f", recorder.CurrentCost())
+	}
+
+	player, err := NewCassette(path)
+	if err != nil {
+		t.Fatalf("NewCassette() error: %v", err)
+	}
+	if got := len(player.Interactions()); got != 3 {
+		t.Fatalf("Expected 3 saved interactions, got: %d", got)
+	}
+
+	resp, err := player.Complete(context.Background(), question("streamed?"))
+	if err != nil {
+		t.Fatalf("Complete() error: %v", err)
+	}
+	if resp.Text != "recorded" || resp.Usage.InputTokens != 100 || resp.Cost != 0 {
+		t.Errorf("Unexpected replayed response: %+v", resp)
+	}
+}
+
+func TestNewCassetteMissingFile(t *testing.T) {
+	if _, err := NewCassette(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing cassette")
+	}
+}
@@ -1,21 +1,74 @@
 package ai
 
-// Tool represents an AI tool interface
-type Tool interface {
-	// CurrentCost returns the current total cost of API usage
-	CurrentCost() float64
+import "context"
+
+// Message is a single conversation turn sent to the model
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+
+	// ToolCalls are the functions an assistant message asked to call
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// ToolResults answer the tool calls of the previous assistant message
+	ToolResults []ToolResult `json:"tool_results,omitempty"`
+}
+
+// Request is a provider independent completion request
+type Request struct {
+	System    string    `json:"system,omitempty"`
+	Messages  []Message `json:"messages"`
+	MaxTokens int       `json:"max_tokens,omitempty"`
+
+	// Tools are the functions the model may call instead of answering
+	Tools []ToolSpec `json:"tools,omitempty"`
+
+	// Cache marks the system prompt and all but the last message as a stable prefix
+	// that providers with prompt caching may reuse across requests
+	Cache bool `json:"cache,omitempty"`
+
+	// Escalate asks a cascade to answer with its large model
+	Escalate bool `json:"escalate,omitempty"`
 }
 
-// MockTool is a mock implementation of the AI Tool interface
-type MockTool struct {
+// Response is the model reply to a Request
+type Response struct {
+	Text  string  `json:"text"`
+	Model string  `json:"model"`
+	Usage Usage   `json:"usage"`
+	Cost  float64 `json:"cost"`
+
+	// ToolCalls are the functions the model asked to call before it can answer
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// Route tells how a cascade picked the model, empty when no cascade answered
+	Route string `json:"route,omitempty"`
 }
 
-// NewMockTool creates a new mock AI tool
-func NewMockTool() Tool {
-	return &MockTool{}
+// StreamEvent is a single event of a streamed response.
+// The stream ends with one event carrying either the final Response or an Err.
+type StreamEvent struct {
+	Delta    string
+	Response *Response
+	Err      error
+
+	// Partial is the usage billed so far, sent before the final Response so a stream that is
+	// cancelled or cut short can still be accounted. A later Partial replaces an earlier one.
+	Partial *Response
+
+	// ToolCall is set when a tool loop runs a function the model called
+	ToolCall *ToolCall
 }
 
-// CurrentCost returns the mock cost
-func (m *MockTool) CurrentCost() float64 {
-	return 0
+// Tool represents an AI tool interface
+type Tool interface {
+	// Complete sends the request to the model and returns its reply
+	Complete(ctx context.Context, req Request) (*Response, error)
+
+	// Stream sends the request to the model and returns its reply as it is generated.
+	// The channel is closed after the final event.
+	Stream(ctx context.Context, req Request) (<-chan StreamEvent, error)
+
+	// CurrentCost returns the current total cost of API usage
+	CurrentCost() float64
 }
@@ -0,0 +1,212 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// compactThreshold is the share of the context window at which old turns are summarized
+	compactThreshold = 0.75
+
+	// keepTurns is the number of recent exchanges that are never summarized
+	keepTurns = 4
+
+	defaultContextWindow = 8192
+)
+
+const summaryPrompt = "You maintain the running memory of a live engineering conversation. " +
+	"Merge the previous summary and the exchanges below into one updated summary. " +
+	"Keep decisions, open questions, names, numbers and technical details. Reply with the summary only."
+
+// contextWindows maps model name prefixes to their context window in tokens
+var contextWindows = map[string]int{
+	"claude":  200000,
+	"gpt-4o":  128000,
+	"gpt-4.1": 1000000,
+}
+
+// ContextWindowFor returns the context window of the model, matching the longest known prefix.
+// Unknown models get a conservative default that fits most local models.
+func ContextWindowFor(model string) int {
+	var best string
+	for prefix := range contextWindows {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return defaultContextWindow
+	}
+	return contextWindows[best]
+}
+
+// EstimateTokens approximates the token count of text at four characters per token
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// Conversation keeps the history of exchanges with the model across requests.
+// When the history grows close to the context window, older exchanges are
+// folded into a running summary so long meetings keep their context.
+type Conversation struct {
+	mu            sync.Mutex
+	summary       string
+	turns         []Message
+	contextWindow int
+}
+
+// NewConversation creates an empty conversation for a model with the given context window
+func NewConversation(contextWindow int) *Conversation {
+	if contextWindow <= 0 {
+		contextWindow = defaultContextWindow
+	}
+
+	return &Conversation{
+		contextWindow: contextWindow,
+	}
+}
+
+// Request builds a request with the history followed by the new user message
+func (c *Conversation) Request(system string, user string) Request {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.request(system, c.turns, user)
+}
+
+// RequestAgain builds a request asking the user message of the newest exchange again,
+// with the history before that exchange. Other messages are asked like in Request.
+func (c *Conversation) RequestAgain(system string, user string) Request {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	turns := c.turns
+	if c.newest(user) {
+		turns = turns[:len(turns)-2]
+	}
+	return c.request(system, turns, user)
+}
+
+func (c *Conversation) request(system string, turns []Message, user string) Request {
+	if c.summary != "" {
+		system += "\n\nSummary of the conversation so far:\n" + c.summary
+	}
+
+	messages := make([]Message, 0, len(turns)+1)
+	messages = append(messages, turns...)
+	messages = append(messages, Message{Role: "user", Content: user})
+
+	return Request{
+		System:   system,
+		Messages: messages,
+	}
+}
+
+// Add records a finished exchange
+func (c *Conversation) Add(user string, assistant string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if assistant == "" {
+		assistant = "(no reply)"
+	}
+	c.turns = append(c.turns,
+		Message{Role: "user", Content: user},
+		Message{Role: "assistant", Content: assistant},
+	)
+}
+
+// Replace swaps the reply of the newest exchange when it asked the user message,
+// otherwise the exchange is added
+func (c *Conversation) Replace(user string, assistant string) {
+	c.mu.Lock()
+	if c.newest(user) {
+		c.turns = c.turns[:len(c.turns)-2]
+	}
+	c.mu.Unlock()
+
+	c.Add(user, assistant)
+}
+
+// newest reports whether the newest exchange asked the user message
+func (c *Conversation) newest(user string) bool {
+	n := len(c.turns)
+	return n >= 2 && c.turns[n-2].Role == "user" && c.turns[n-2].Content == user
+}
+
+// Tokens estimates the size of the summary and history
+func (c *Conversation) Tokens() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokens()
+}
+
+func (c *Conversation) tokens() int {
+	total := EstimateTokens(c.summary)
+	for _, turn := range c.turns {
+		total += EstimateTokens(turn.Content)
+	}
+	return total
+}
+
+// Summary returns the running summary of compacted exchanges
+func (c *Conversation) Summary() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.summary
+}
+
+// Compact summarizes all but the most recent exchanges once the history nears the context window.
+// It returns the summarization response so its cost can be recorded, or nil when nothing was done.
+func (c *Conversation) Compact(ctx context.Context, tool Tool) (*Response, error) {
+	c.mu.Lock()
+	if float64(c.tokens()) < compactThreshold*float64(c.contextWindow) || len(c.turns) <= keepTurns*2 {
+		c.mu.Unlock()
+		return nil, nil
+	}
+	old := c.turns[:len(c.turns)-keepTurns*2]
+	summary := c.summary
+	c.mu.Unlock()
+
+	var prompt strings.Builder
+	if summary != "" {
+		fmt.Fprintf(&prompt, "Previous summary:\n%s\n\n", summary)
+	}
+	prompt.WriteString("Exchanges:\n")
+	for _, turn := range old {
+		fmt.Fprintf(&prompt, "%s: %s\n", turn.Role, turn.Content)
+	}
+
+	resp, err := tool.Complete(ctx, Request{
+		System:   summaryPrompt,
+		Messages: []Message{{Role: "user", Content: prompt.String()}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize conversation: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The conversation was reset while summarizing
+	if len(c.turns) < len(old) {
+		return resp, nil
+	}
+
+	// Exchanges added while summarizing stay after the compacted ones
+	c.turns = append([]Message(nil), c.turns[len(old):]...)
+	c.summary = strings.TrimSpace(resp.Text)
+
+	return resp, nil
+}
+
+// Reset forgets the summary and history
+func (c *Conversation) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.summary = ""
+	c.turns = nil
+}
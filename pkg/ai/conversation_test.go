@@ -0,0 +1,126 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type stubTool struct {
+	Tool
+	text     string
+	requests []Request
+}
+
+func (s *stubTool) Complete(ctx context.Context, req Request) (*Response, error) {
+	s.requests = append(s.requests, req)
+	return &Response{Text: s.text, Model: "stub"}, nil
+}
+
+func TestConversationRequestIncludesHistory(t *testing.T) {
+	c := NewConversation(1000)
+	c.Add("first question", "first answer")
+
+	req := c.Request("system", "second question")
+
+	if req.System != "system" {
+		t.Errorf("Expected plain system prompt without summary, got: %s", req.System)
+	}
+	if len(req.Messages) != 3 {
+		t.Fatalf("Expected 3 messages, got: %d", len(req.Messages))
+	}
+	if req.Messages[0].Content != "first question" || req.Messages[1].Role != "assistant" || req.Messages[2].Content != "second question" {
+		t.Errorf("Unexpected messages: %+v", req.Messages)
+	}
+}
+
+func TestConversationAsksNewestExchangeAgain(t *testing.T) {
+	c := NewConversation(1000)
+	c.Add("first question", "first answer")
+	c.Add("second question", "quick answer")
+
+	req := c.RequestAgain("system", "second question")
+	if len(req.Messages) != 3 || req.Messages[2].Content != "second question" {
+		t.Fatalf("Expected the history before the newest exchange, got: %+v", req.Messages)
+	}
+
+	c.Replace("second question", "thorough answer")
+	req = c.Request("system", "third question")
+	if len(req.Messages) != 5 || req.Messages[3].Content != "thorough answer" {
+		t.Errorf("Expected the newest reply replaced, got: %+v", req.Messages)
+	}
+
+	if req := c.RequestAgain("system", "other question"); len(req.Messages) != 5 {
+		t.Errorf("Expected the full history for another message, got: %+v", req.Messages)
+	}
+}
+
+func TestConversationCompactBelowThreshold(t *testing.T) {
+	c := NewConversation(100000)
+	tool := &stubTool{text: "summary"}
+
+	for i := 0; i < 10; i++ {
+		c.Add("question", "answer")
+	}
+
+	resp, err := c.Compact(context.Background(), tool)
+	if err != nil {
+		t.Fatalf("Compact() error: %v", err)
+	}
+	if resp != nil || len(tool.requests) != 0 {
+		t.Error("Expected no summarization below the threshold")
+	}
+}
+
+func TestConversationCompactSummarizesOldTurns(t *testing.T) {
+	c := NewConversation(200)
+	tool := &stubTool{text: "  the running summary  "}
+
+	long := strings.Repeat("word ", 20)
+	for i := 0; i < 8; i++ {
+		c.Add(long+"question", long+"answer")
+	}
+	c.Add("latest question", "latest answer")
+
+	resp, err := c.Compact(context.Background(), tool)
+	if err != nil {
+		t.Fatalf("Compact() error: %v", err)
+	}
+	if resp == nil || len(tool.requests) != 1 {
+		t.Fatal("Expected a summarization request")
+	}
+
+	if c.Summary() != "the running summary" {
+		t.Errorf("Expected trimmed summary, got: %q", c.Summary())
+	}
+
+	req := c.Request("system", "next")
+	if !strings.Contains(req.System, "the running summary") {
+		t.Errorf("Expected summary in the system prompt, got: %s", req.System)
+	}
+	if len(req.Messages) != keepTurns*2+1 {
+		t.Errorf("Expected %d messages after compaction, got: %d", keepTurns*2+1, len(req.Messages))
+	}
+	if req.Messages[len(req.Messages)-3].Content != "latest question" {
+		t.Errorf("Expected recent turns to be kept verbatim, got: %+v", req.Messages)
+	}
+}
+
+func TestConversationReset(t *testing.T) {
+	c := NewConversation(1000)
+	c.Add("question", "answer")
+	c.Reset()
+
+	if c.Tokens() != 0 {
+		t.Errorf("Expected empty conversation after reset, got: %d tokens", c.Tokens())
+	}
+}
+
+func TestContextWindowFor(t *testing.T) {
+	if ContextWindowFor("claude-sonnet-4-5") != 200000 {
+		t.Error("Expected claude models to have a 200k context window")
+	}
+	if ContextWindowFor("llama3.1") != defaultContextWindow {
+		t.Error("Expected unknown models to use the default context window")
+	}
+}
@@ -0,0 +1,234 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrUnreachable wraps errors of requests that got no complete response from the provider,
+// like a dropped network, a connection that hangs until the deadline or a stream cut short
+var ErrUnreachable = errors.New("provider unreachable")
+
+// APIError is returned when a provider answers with a non-success status
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Type       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("%s api error (%d %s): %s", e.Provider, e.StatusCode, e.Type, e.Message)
+	}
+	return fmt.Sprintf("%s api error (%d): %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// RetryError is returned once a request has failed on every attempt
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("request failed after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// RetryPolicy controls how rate limited, overloaded and failed requests are retried
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// DefaultRetryPolicy is used by all clients unless overridden
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 4,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   30 * time.Second,
+}
+
+// postJSON encodes the body, posts it and returns the response once the status is OK.
+// Transport errors, 429 and 5xx responses are retried with exponential backoff.
+func postJSON(ctx context.Context, client *http.Client, policy RetryPolicy, provider string, url string, header http.Header, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		req.Header.Set("content-type", "application/json")
+
+		var retryAfter time.Duration
+		resp, err := client.Do(req)
+		if err != nil {
+			// Past the deadline there is no time left to retry
+			if ctx.Err() != nil {
+				return nil, unreachable(ctx, fmt.Errorf("failed to send request: %w", ctx.Err()))
+			}
+			err = unreachable(ctx, fmt.Errorf("failed to send request: %w", err))
+		} else if resp.StatusCode != http.StatusOK {
+			apiErr := newAPIError(provider, resp)
+			resp.Body.Close()
+			if !retryable(resp.StatusCode) {
+				return nil, apiErr
+			}
+			retryAfter = parseRetryAfter(resp.Header.Get("retry-after"), time.Now())
+			err = apiErr
+		} else {
+			return resp, nil
+		}
+
+		if attempt >= policy.MaxRetries {
+			return nil, &RetryError{Attempts: attempt + 1, Err: err}
+		}
+
+		delay := policy.backoff(attempt)
+		if retryAfter > 0 {
+			delay = retryAfter
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, unreachable(ctx, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// unreachable marks a failure to get a complete response as ErrUnreachable, including
+// timeouts and connections dropped mid-stream. Cancelled requests are returned as they are.
+func unreachable(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.Canceled) || errors.Is(err, context.Canceled) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrUnreachable, err)
+}
+
+// retryable reports whether a status is worth retrying: rate limits, overload (529) and server errors
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// backoff returns the exponential delay for the attempt with jitter in its upper half
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << attempt
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + rand.N(half)
+}
+
+// parseRetryAfter reads a retry-after header given either in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+
+	return 0
+}
+
+// newAPIError reads the error body in the shapes used by the supported providers
+func newAPIError(provider string, resp *http.Response) *APIError {
+	data, _ := io.ReadAll(resp.Body)
+	apiErr := &APIError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Message:    string(bytes.TrimSpace(data)),
+	}
+
+	var nested struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(data, &nested) == nil && nested.Error.Message != "" {
+		apiErr.Type = nested.Error.Type
+		apiErr.Message = nested.Error.Message
+		return apiErr
+	}
+
+	var flat struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &flat) == nil && flat.Error != "" {
+		apiErr.Message = flat.Error
+	}
+
+	return apiErr
+}
+
+// newLineScanner returns a scanner for line based streams with room for long events
+func newLineScanner(body io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return scanner
+}
+
+// meter prices responses and keeps the running total for CurrentCost
+type meter struct {
+	mu    sync.Mutex
+	total float64
+}
+
+// account prices the response and adds it to the running total
+func (m *meter) account(resp *Response) *Response {
+	resp.Cost = Cost(resp.Model, resp.Usage)
+	return m.charge(resp)
+}
+
+// charge adds the cost of an already priced response to the running total
+func (m *meter) charge(resp *Response) *Response {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.total += resp.Cost
+
+	return resp
+}
+
+// CurrentCost returns the total cost of API usage so far
+func (m *meter) CurrentCost() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.total
+}
@@ -0,0 +1,181 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxRetries: 2,
+	BaseDelay:  time.Millisecond,
+	MaxDelay:   5 * time.Millisecond,
+}
+
+func TestPostJSONRetriesOverloaded(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(529)
+			w.Write([]byte(`{"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	resp, err := postJSON(context.Background(), srv.Client(), testRetryPolicy, "test", srv.URL, nil, struct{}{})
+	if err != nil {
+		t.Fatalf("postJSON() error: %v", err)
+	}
+	resp.Body.Close()
+
+	if calls.Load() != 3 {
+		t.Errorf("Expected 3 attempts, got: %d", calls.Load())
+	}
+}
+
+func TestPostJSONRetriesExhausted(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("retry-after", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"type": "error", "error": {"type": "rate_limit_error", "message": "slow down"}}`))
+	}))
+	defer srv.Close()
+
+	_, err := postJSON(context.Background(), srv.Client(), testRetryPolicy, "test", srv.URL, nil, struct{}{})
+
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("Expected RetryError, got: %v", err)
+	}
+	if retryErr.Attempts != 3 || calls.Load() != 3 {
+		t.Errorf("Expected 3 attempts, got: %d (%d calls)", retryErr.Attempts, calls.Load())
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Type != "rate_limit_error" {
+		t.Errorf("Expected wrapped rate limit APIError, got: %v", err)
+	}
+}
+
+func TestPostJSONReportsUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := srv.URL
+	srv.Close()
+
+	_, err := postJSON(context.Background(), http.DefaultClient, testRetryPolicy, "test", url, nil, struct{}{})
+	if !errors.Is(err, ErrUnreachable) {
+		t.Errorf("Expected ErrUnreachable for a closed server, got: %v", err)
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		t.Errorf("Expected no APIError without a response, got: %v", apiErr)
+	}
+}
+
+func TestPostJSONReportsHungServerUnreachable(t *testing.T) {
+	hang := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Accepts the connection and never replies
+		<-hang
+	}))
+	defer srv.Close()
+	defer close(hang)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := postJSON(ctx, srv.Client(), testRetryPolicy, "test", srv.URL, nil, struct{}{})
+	if !errors.Is(err, ErrUnreachable) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected an unreachable deadline error, got: %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	_, err = postJSON(ctx, srv.Client(), testRetryPolicy, "test", srv.URL, nil, struct{}{})
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrUnreachable) {
+		t.Errorf("Expected a cancelled request not to be unreachable, got: %v", err)
+	}
+}
+
+func TestPostJSONDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"type": "error", "error": {"type": "invalid_request_error", "message": "bad"}}`))
+	}))
+	defer srv.Close()
+
+	_, err := postJSON(context.Background(), srv.Client(), testRetryPolicy, "test", srv.URL, nil, struct{}{})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected APIError, got: %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected a single attempt, got: %d", calls.Load())
+	}
+}
+
+func TestPostJSONStopsOnContextCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("retry-after", "10")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := postJSON(ctx, srv.Client(), testRetryPolicy, "test", srv.URL, nil, struct{}{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got: %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Expected retry-after wait to be cut short by the context")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"0.5", 500 * time.Millisecond},
+		{"-1", 0},
+		{"Wed, 01 Jan 2025 12:00:10 GMT", 10 * time.Second},
+		{"Wed, 01 Jan 2025 11:00:00 GMT", 0},
+		{"soon", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.expected {
+			t.Errorf("parseRetryAfter(%q) = %v, expected %v", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestBackoffIsBounded(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for attempt := 0; attempt < 10; attempt++ {
+		delay := policy.backoff(attempt)
+		if delay <= 0 || delay > policy.MaxDelay {
+			t.Errorf("backoff(%d) = %v, expected within (0, %v]", attempt, delay, policy.MaxDelay)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultOllamaURL   = "http://localhost:11434"
+	defaultOllamaModel = "llama3.1"
+)
+
+// OllamaClient talks to a local Ollama server, so nothing leaves the machine
+type OllamaClient struct {
+	model      string
+	baseURL    string
+	httpClient *http.Client
+	retry      RetryPolicy
+
+	meter
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []openAITool    `json:"tools,omitempty"`
+	Stream   bool            `json:"stream"`
+	Options  map[string]any  `json:"options,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+// ollamaToolCall is a function call, Ollama sends whole calls without IDs
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaResponse struct {
+	Model           string        `json:"model"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// NewOllamaClient creates a new Ollama chat client.
+// Empty model and baseURL fall back to the defaults.
+func NewOllamaClient(model string, baseURL string) *OllamaClient {
+	if model == "" {
+		model = defaultOllamaModel
+	}
+	if baseURL == "" {
+		baseURL = defaultOllamaURL
+	}
+
+	return &OllamaClient{
+		model:      model,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{},
+		retry:      DefaultRetryPolicy,
+	}
+}
+
+// Complete sends the request to the Ollama chat API and returns the text reply
+func (c *OllamaClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	resp, err := c.send(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var chat ollamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chat); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return c.account(c.response(chat, chat.Message.Content, chat.Message.ToolCalls)), nil
+}
+
+// Stream sends the request to the Ollama chat API and emits text deltas as they arrive
+func (c *OllamaClient) Stream(ctx context.Context, req Request) (<-chan StreamEvent, error) {
+	resp, err := c.send(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		final, err := c.readStream(ctx, resp.Body, events)
+		if err != nil {
+			final = StreamEvent{Err: err}
+		}
+
+		select {
+		case events <- final:
+		case <-ctx.Done():
+		}
+	}()
+
+	return events, nil
+}
+
+// readStream forwards text deltas from the newline delimited JSON stream and returns the final event
+func (c *OllamaClient) readStream(ctx context.Context, body io.Reader, events chan<- StreamEvent) (StreamEvent, error) {
+	var text strings.Builder
+	var calls []ollamaToolCall
+
+	scanner := newLineScanner(body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var chunk ollamaResponse
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			return StreamEvent{}, fmt.Errorf("failed to decode stream event: %w", err)
+		}
+		if chunk.Error != "" {
+			return StreamEvent{}, fmt.Errorf("ollama stream error: %s", chunk.Error)
+		}
+
+		calls = append(calls, chunk.Message.ToolCalls...)
+
+		if delta := chunk.Message.Content; delta != "" {
+			text.WriteString(delta)
+			select {
+			case events <- StreamEvent{Delta: delta}:
+			case <-ctx.Done():
+				return StreamEvent{}, ctx.Err()
+			}
+		}
+
+		if chunk.Done {
+			return StreamEvent{Response: c.account(c.response(chunk, text.String(), calls))}, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return StreamEvent{}, unreachable(ctx, fmt.Errorf("failed to read stream: %w", err))
+	}
+
+	return StreamEvent{}, unreachable(ctx, fmt.Errorf("stream ended before done"))
+}
+
+func (c *OllamaClient) response(chat ollamaResponse, text string, calls []ollamaToolCall) *Response {
+	model := chat.Model
+	if model == "" {
+		model = c.model
+	}
+
+	response := &Response{
+		Text:  text,
+		Model: model,
+		Usage: Usage{
+			InputTokens:  chat.PromptEvalCount,
+			OutputTokens: chat.EvalCount,
+		},
+	}
+	for i, call := range calls {
+		input := call.Function.Arguments
+		if len(input) == 0 || string(input) == "null" {
+			input = json.RawMessage("{}")
+		}
+		response.ToolCalls = append(response.ToolCalls, ToolCall{
+			ID:    fmt.Sprintf("call_%d", i+1),
+			Name:  call.Function.Name,
+			Input: input,
+		})
+	}
+	return response
+}
+
+// send posts the request and returns the response once the status is OK
+func (c *OllamaClient) send(ctx context.Context, req Request, stream bool) (*http.Response, error) {
+	body := ollamaRequest{
+		Model:    c.model,
+		Messages: toOllamaMessages(req),
+		Tools:    toOpenAITools(req.Tools),
+		Stream:   stream,
+	}
+	if req.MaxTokens > 0 {
+		body.Options = map[string]any{"num_predict": req.MaxTokens}
+	}
+
+	return postJSON(ctx, c.httpClient, c.retry, "ollama", c.baseURL+"/api/chat", nil, body)
+}
+
+// toOllamaMessages converts the messages, sending each tool result as its own tool message
+func toOllamaMessages(req Request) []ollamaMessage {
+	var messages []ollamaMessage
+	for _, msg := range withSystemMessage(req) {
+		if len(msg.ToolResults) > 0 {
+			for _, result := range msg.ToolResults {
+				messages = append(messages, ollamaMessage{Role: "tool", Content: result.Content, ToolName: result.Name})
+			}
+			continue
+		}
+
+		message := ollamaMessage{Role: msg.Role, Content: msg.Content}
+		for _, call := range msg.ToolCalls {
+			var toolCall ollamaToolCall
+			toolCall.Function.Name = call.Name
+			toolCall.Function.Arguments = call.Input
+			message.ToolCalls = append(message.ToolCalls, toolCall)
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
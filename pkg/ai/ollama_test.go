@@ -0,0 +1,152 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOllamaComplete(t *testing.T) {
+	var got ollamaRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("Expected path /api/chat, got: %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("Decode() error: %v", err)
+		}
+
+		w.Write([]byte(`{
+			"model": "llama3.1",
+			"message": {"role": "assistant", "content": "Hello"},
+			"done": true,
+			"prompt_eval_count": 8,
+			"eval_count": 2
+		}`))
+	}))
+	defer srv.Close()
+
+	client := NewOllamaClient("", srv.URL)
+	resp, err := client.Complete(context.Background(), Request{
+		System:    "be brief",
+		Messages:  []Message{{Role: "user", Content: "hi"}},
+		MaxTokens: 64,
+	})
+	if err != nil {
+		t.Fatalf("Complete() error: %v", err)
+	}
+
+	if resp.Text != "Hello" || resp.Usage.InputTokens != 8 || resp.Usage.OutputTokens != 2 {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+	if resp.Cost != 0 {
+		t.Errorf("Expected local model to be free, got: %f", resp.Cost)
+	}
+	if got.Stream {
+		t.Error("Expected stream to be disabled")
+	}
+	if got.Options["num_predict"] != float64(64) {
+		t.Errorf("Expected num_predict option, got: %v", got.Options)
+	}
+}
+
+func TestOllamaStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(
+			`{"model": "llama3.1", "message": {"role": "assistant", "content": "Hel"}, "done": false}` + "\n" +
+				`{"model": "llama3.1", "message": {"role": "assistant", "content": "lo"}, "done": false}` + "\n" +
+				`{"model": "llama3.1", "message": {"role": "assistant", "content": ""}, "done": true, "prompt_eval_count": 8, "eval_count": 2}` + "\n"))
+	}))
+	defer srv.Close()
+
+	client := NewOllamaClient("", srv.URL)
+	events, err := client.Stream(context.Background(), Request{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("Stream() error: %v", err)
+	}
+
+	var deltas []string
+	var final *Response
+	for event := range events {
+		if event.Err != nil {
+			t.Fatalf("Stream event error: %v", event.Err)
+		}
+		if event.Response != nil {
+			final = event.Response
+			continue
+		}
+		deltas = append(deltas, event.Delta)
+	}
+
+	if len(deltas) != 2 {
+		t.Errorf("Expected 2 deltas, got: %v", deltas)
+	}
+	if final == nil || final.Text != "Hello" || final.Usage.InputTokens != 8 {
+		t.Errorf("Unexpected final response: %+v", final)
+	}
+}
+
+func TestOllamaError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "model 'missing' not found"}`))
+	}))
+	defer srv.Close()
+
+	client := NewOllamaClient("missing", srv.URL)
+	_, err := client.Complete(context.Background(), Request{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected APIError, got: %v", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "model 'missing' not found" {
+		t.Errorf("Unexpected error: %+v", apiErr)
+	}
+}
+
+func TestOllamaToolCalls(t *testing.T) {
+	var got ollamaRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("Decode() error: %v", err)
+		}
+
+		w.Write([]byte(`{
+			"model": "llama3.1",
+			"message": {"role": "assistant", "content": "", "tool_calls": [{"function": {"name": "calculate", "arguments": {"expression": "2*3"}}}]},
+			"done": true
+		}`))
+	}))
+	defer srv.Close()
+
+	client := NewOllamaClient("", srv.URL)
+	resp, err := client.Complete(context.Background(), Request{
+		Messages: []Message{
+			{Role: "user", Content: "what is 2*3?"},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Name: "calculate", Input: json.RawMessage(`{"expression":"1+1"}`)}}},
+			{Role: "user", ToolResults: []ToolResult{{CallID: "call_1", Name: "calculate", Content: "2"}}},
+		},
+		Tools: []ToolSpec{{Name: "calculate", Description: "math", Parameters: json.RawMessage(`{"type":"object"}`)}},
+	})
+	if err != nil {
+		t.Fatalf("Complete() error: %v", err)
+	}
+
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "call_1" || resp.ToolCalls[0].Name != "calculate" {
+		t.Fatalf("Expected one tool call with a generated ID, got: %+v", resp.ToolCalls)
+	}
+	if len(got.Tools) != 1 || got.Tools[0].Function.Name != "calculate" {
+		t.Errorf("Expected the tool in the request, got: %+v", got.Tools)
+	}
+	if len(got.Messages) != 3 || got.Messages[2].Role != "tool" || got.Messages[2].ToolName != "calculate" {
+		t.Errorf("Expected the result as a tool message, got: %+v", got.Messages)
+	}
+}
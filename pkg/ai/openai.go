@@ -0,0 +1,334 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultOpenAIURL   = "https://api.openai.com"
+	defaultOpenAIModel = "gpt-4o-mini"
+)
+
+// OpenAIClient talks to an OpenAI compatible chat completions API.
+// This also covers llama.cpp server and vLLM, which expose the same endpoint.
+type OpenAIClient struct {
+	apiKey     string
+	model      string
+	baseURL    string
+	httpClient *http.Client
+	retry      RetryPolicy
+
+	meter
+}
+
+type openAIRequest struct {
+	Model         string               `json:"model"`
+	Messages      []openAIMessage      `json:"messages"`
+	MaxTokens     int                  `json:"max_tokens,omitempty"`
+	Tools         []openAITool         `json:"tools,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// openAITool describes a function, the same shape is used by Ollama
+type openAITool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		Parameters  json.RawMessage `json:"parameters"`
+	} `json:"function"`
+}
+
+// openAIToolCall is a function call, streamed calls arrive in fragments keyed by index
+type openAIToolCall struct {
+	Index    *int   `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type openAIResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+		Delta   openAIMessage `json:"delta"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+// NewOpenAIClient creates a new chat completions client.
+// The API key may be empty for local servers. Empty model and baseURL fall back to the defaults.
+func NewOpenAIClient(apiKey string, model string, baseURL string) *OpenAIClient {
+	if model == "" {
+		model = defaultOpenAIModel
+	}
+	if baseURL == "" {
+		baseURL = defaultOpenAIURL
+	}
+
+	return &OpenAIClient{
+		apiKey:     apiKey,
+		model:      model,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{},
+		retry:      DefaultRetryPolicy,
+	}
+}
+
+// Complete sends the request to the chat completions API and returns the text reply
+func (c *OpenAIClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	resp, err := c.send(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var completion openAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	response := &Response{Model: completion.Model}
+	if len(completion.Choices) > 0 {
+		message := completion.Choices[0].Message
+		response.Text = message.Content
+		for _, call := range message.ToolCalls {
+			response.ToolCalls = append(response.ToolCalls, toolCall(call))
+		}
+	}
+	if completion.Usage != nil {
+		response.Usage = Usage{
+			InputTokens:  completion.Usage.PromptTokens,
+			OutputTokens: completion.Usage.CompletionTokens,
+		}
+	}
+
+	return c.account(response), nil
+}
+
+// Stream sends the request to the chat completions API and emits text deltas as they arrive
+func (c *OpenAIClient) Stream(ctx context.Context, req Request) (<-chan StreamEvent, error) {
+	resp, err := c.send(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		final, err := c.readStream(ctx, resp.Body, events, estimateInput(req))
+		if err != nil {
+			final = StreamEvent{Err: err}
+		}
+
+		select {
+		case events <- final:
+		case <-ctx.Done():
+		}
+	}()
+
+	return events, nil
+}
+
+// readStream forwards text deltas from the event stream and returns the final event.
+// Usage only arrives at the end, so the estimated input tokens are reported as billed up front.
+func (c *OpenAIClient) readStream(ctx context.Context, body io.Reader, events chan<- StreamEvent, inputTokens int) (StreamEvent, error) {
+	response := &Response{Model: c.model}
+	var text strings.Builder
+	var calls []openAIToolCall
+
+	partial := &Response{Model: c.model, Usage: Usage{InputTokens: inputTokens}}
+	partial.Cost = Cost(partial.Model, partial.Usage)
+	select {
+	case events <- StreamEvent{Partial: partial}:
+	case <-ctx.Done():
+		return StreamEvent{}, ctx.Err()
+	}
+
+	scanner := newLineScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			response.Text = text.String()
+			for _, call := range calls {
+				response.ToolCalls = append(response.ToolCalls, toolCall(call))
+			}
+			return StreamEvent{Response: c.account(response)}, nil
+		}
+
+		var chunk openAIResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return StreamEvent{}, fmt.Errorf("failed to decode stream event: %w", err)
+		}
+
+		if chunk.Model != "" {
+			response.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			response.Usage = Usage{
+				InputTokens:  chunk.Usage.PromptTokens,
+				OutputTokens: chunk.Usage.CompletionTokens,
+			}
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		for _, fragment := range chunk.Choices[0].Delta.ToolCalls {
+			calls = mergeToolCall(calls, fragment)
+		}
+		if chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta.Content
+		text.WriteString(delta)
+		select {
+		case events <- StreamEvent{Delta: delta}:
+		case <-ctx.Done():
+			return StreamEvent{}, ctx.Err()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return StreamEvent{}, unreachable(ctx, fmt.Errorf("failed to read stream: %w", err))
+	}
+
+	return StreamEvent{}, unreachable(ctx, fmt.Errorf("stream ended before [DONE]"))
+}
+
+// send posts the request and returns the response once the status is OK
+func (c *OpenAIClient) send(ctx context.Context, req Request, stream bool) (*http.Response, error) {
+	body := openAIRequest{
+		Model:     c.model,
+		Messages:  toOpenAIMessages(req),
+		MaxTokens: req.MaxTokens,
+		Tools:     toOpenAITools(req.Tools),
+		Stream:    stream,
+	}
+	if stream {
+		body.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+
+	header := http.Header{}
+	if c.apiKey != "" {
+		header.Set("authorization", "Bearer "+c.apiKey)
+	}
+
+	return postJSON(ctx, c.httpClient, c.retry, "openai", c.baseURL+"/v1/chat/completions", header, body)
+}
+
+// withSystemMessage returns the messages with the system prompt as the leading message,
+// which is how chat APIs without a separate system field expect it
+func withSystemMessage(req Request) []Message {
+	if req.System == "" {
+		return req.Messages
+	}
+
+	messages := make([]Message, 0, len(req.Messages)+1)
+	messages = append(messages, Message{Role: "system", Content: req.System})
+	return append(messages, req.Messages...)
+}
+
+// toOpenAIMessages converts the messages, sending each tool result as its own tool message
+func toOpenAIMessages(req Request) []openAIMessage {
+	var messages []openAIMessage
+	for _, msg := range withSystemMessage(req) {
+		if len(msg.ToolResults) > 0 {
+			for _, result := range msg.ToolResults {
+				messages = append(messages, openAIMessage{Role: "tool", Content: result.Content, ToolCallID: result.CallID})
+			}
+			continue
+		}
+
+		message := openAIMessage{Role: msg.Role, Content: msg.Content}
+		for _, call := range msg.ToolCalls {
+			toolCall := openAIToolCall{ID: call.ID, Type: "function"}
+			toolCall.Function.Name = call.Name
+			toolCall.Function.Arguments = string(call.Input)
+			message.ToolCalls = append(message.ToolCalls, toolCall)
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+func toOpenAITools(specs []ToolSpec) []openAITool {
+	var tools []openAITool
+	for _, spec := range specs {
+		tool := openAITool{Type: "function"}
+		tool.Function.Name = spec.Name
+		tool.Function.Description = spec.Description
+		tool.Function.Parameters = spec.Parameters
+		tools = append(tools, tool)
+	}
+	return tools
+}
+
+// mergeToolCall adds a streamed fragment to the call with the same index
+func mergeToolCall(calls []openAIToolCall, fragment openAIToolCall) []openAIToolCall {
+	for i := range calls {
+		if calls[i].Index != nil && fragment.Index != nil && *calls[i].Index == *fragment.Index {
+			if fragment.ID != "" {
+				calls[i].ID = fragment.ID
+			}
+			calls[i].Function.Name += fragment.Function.Name
+			calls[i].Function.Arguments += fragment.Function.Arguments
+			return calls
+		}
+	}
+	return append(calls, fragment)
+}
+
+func toolCall(call openAIToolCall) ToolCall {
+	input := json.RawMessage(call.Function.Arguments)
+	if len(input) == 0 {
+		input = json.RawMessage("{}")
+	}
+	return ToolCall{ID: call.ID, Name: call.Function.Name, Input: input}
+}
+
+// estimateInput estimates the prompt tokens of a request
+func estimateInput(req Request) int {
+	tokens := EstimateTokens(req.System)
+	for _, msg := range req.Messages {
+		tokens += EstimateTokens(msg.Content)
+		for _, call := range msg.ToolCalls {
+			tokens += EstimateTokens(string(call.Input))
+		}
+		for _, result := range msg.ToolResults {
+			tokens += EstimateTokens(result.Content)
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,159 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIComplete(t *testing.T) {
+	var got openAIRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Expected path /v1/chat/completions, got: %s", r.URL.Path)
+		}
+		if r.Header.Get("authorization") != "Bearer test-key" {
+			t.Errorf("Expected bearer token, got: %s", r.Header.Get("authorization"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("Decode() error: %v", err)
+		}
+
+		w.Write([]byte(`{
+			"model": "gpt-4o-mini",
+			"choices": [{"message": {"role": "assistant", "content": "Hello"}}],
+			"usage": {"prompt_tokens": 10, "completion_tokens": 3}
+		}`))
+	}))
+	defer srv.Close()
+
+	client := NewOpenAIClient("test-key", "gpt-4o-mini", srv.URL)
+	resp, err := client.Complete(context.Background(), Request{
+		System:   "be brief",
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("Complete() error: %v", err)
+	}
+
+	if resp.Text != "Hello" {
+		t.Errorf("Expected 'Hello', got: %s", resp.Text)
+	}
+	if resp.Usage.InputTokens != 10 || resp.Usage.OutputTokens != 3 {
+		t.Errorf("Expected usage to be parsed, got: %+v", resp.Usage)
+	}
+	if len(got.Messages) != 2 || got.Messages[0].Role != "system" || got.Messages[0].Content != "be brief" {
+		t.Errorf("Expected system prompt as leading message, got: %+v", got.Messages)
+	}
+}
+
+func TestOpenAIStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("authorization") != "" {
+			t.Errorf("Expected no authorization header without api key, got: %s", r.Header.Get("authorization"))
+		}
+
+		w.Header().Set("content-type", "text/event-stream")
+		w.Write([]byte(
+			`data: {"model": "local", "choices": [{"delta": {"role": "assistant"}}]}` + "\n\n" +
+				`data: {"model": "local", "choices": [{"delta": {"content": "Hel"}}]}` + "\n\n" +
+				`data: {"model": "local", "choices": [{"delta": {"content": "lo"}}]}` + "\n\n" +
+				`data: {"model": "local", "choices": [], "usage": {"prompt_tokens": 5, "completion_tokens": 2}}` + "\n\n" +
+				"data: [DONE]\n\n"))
+	}))
+	defer srv.Close()
+
+	client := NewOpenAIClient("", "local", srv.URL)
+	events, err := client.Stream(context.Background(), Request{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("Stream() error: %v", err)
+	}
+
+	var deltas []string
+	var partial, final *Response
+	for event := range events {
+		if event.Err != nil {
+			t.Fatalf("Stream event error: %v", event.Err)
+		}
+		if event.Partial != nil {
+			partial = event.Partial
+			continue
+		}
+		if event.Response != nil {
+			final = event.Response
+			continue
+		}
+		deltas = append(deltas, event.Delta)
+	}
+
+	if partial == nil || partial.Usage.InputTokens != 1 {
+		t.Errorf("Expected the estimated input usage up front, got: %+v", partial)
+	}
+	if len(deltas) != 2 {
+		t.Errorf("Expected 2 deltas, got: %v", deltas)
+	}
+	if final == nil || final.Text != "Hello" || final.Usage.OutputTokens != 2 {
+		t.Errorf("Unexpected final response: %+v", final)
+	}
+}
+
+func TestOpenAIToolCalls(t *testing.T) {
+	var got openAIRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("Decode() error: %v", err)
+		}
+
+		w.Header().Set("content-type", "text/event-stream")
+		w.Write([]byte(
+			`data: {"model": "gpt-4o-mini", "choices": [{"delta": {"role": "assistant", "tool_calls": [{"index": 0, "id": "call_a", "type": "function", "function": {"name": "calculate", "arguments": ""}}]}}]}` + "\n\n" +
+				`data: {"model": "gpt-4o-mini", "choices": [{"delta": {"tool_calls": [{"index": 0, "function": {"arguments": "{\"expression\":"}}]}}]}` + "\n\n" +
+				`data: {"model": "gpt-4o-mini", "choices": [{"delta": {"tool_calls": [{"index": 0, "function": {"arguments": "\"2*3\"}"}}]}}]}` + "\n\n" +
+				"data: [DONE]\n\n"))
+	}))
+	defer srv.Close()
+
+	client := NewOpenAIClient("test-key", "gpt-4o-mini", srv.URL)
+	events, err := client.Stream(context.Background(), Request{
+		Messages: []Message{
+			{Role: "user", Content: "what is 1+1?"},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_0", Name: "calculate", Input: json.RawMessage(`{"expression":"1+1"}`)}}},
+			{Role: "user", ToolResults: []ToolResult{{CallID: "call_0", Name: "calculate", Content: "2"}}},
+		},
+		Tools: []ToolSpec{{Name: "calculate", Description: "math", Parameters: json.RawMessage(`{"type":"object"}`)}},
+	})
+	if err != nil {
+		t.Fatalf("Stream() error: %v", err)
+	}
+
+	var final *Response
+	for event := range events {
+		if event.Err != nil {
+			t.Fatalf("Stream event error: %v", event.Err)
+		}
+		if event.Response != nil {
+			final = event.Response
+		}
+	}
+
+	if final == nil || len(final.ToolCalls) != 1 {
+		t.Fatalf("Expected one tool call, got: %+v", final)
+	}
+	if call := final.ToolCalls[0]; call.ID != "call_a" || call.Name != "calculate" || string(call.Input) != `{"expression":"2*3"}` {
+		t.Errorf("Expected the streamed fragments joined, got: %+v", call)
+	}
+
+	if len(got.Tools) != 1 || got.Tools[0].Type != "function" || got.Tools[0].Function.Name != "calculate" {
+		t.Errorf("Expected the tool as a function, got: %+v", got.Tools)
+	}
+	if len(got.Messages) != 3 || got.Messages[1].ToolCalls[0].Function.Arguments != `{"expression":"1+1"}` {
+		t.Fatalf("Expected the assistant tool call, got: %+v", got.Messages)
+	}
+	if got.Messages[2].Role != "tool" || got.Messages[2].ToolCallID != "call_0" || got.Messages[2].Content != "2" {
+		t.Errorf("Expected the result as a tool message, got: %+v", got.Messages[2])
+	}
+}
@@ -0,0 +1,68 @@
+package ai
+
+import "strings"
+
+// Usage holds the token counts reported for a single request.
+// InputTokens excludes tokens written to or read from the prompt cache.
+type Usage struct {
+	InputTokens      int `json:"input_tokens"`
+	OutputTokens     int `json:"output_tokens"`
+	CacheWriteTokens int `json:"cache_creation_input_tokens"`
+	CacheReadTokens  int `json:"cache_read_input_tokens"`
+}
+
+// Price is the cost in USD per million tokens
+type Price struct {
+	Input      float64
+	Output     float64
+	CacheWrite float64
+	CacheRead  float64
+}
+
+// prices maps model name prefixes to their per-token prices.
+// Dated model IDs such as claude-3-5-haiku-20241022 match by prefix.
+var prices = map[string]Price{
+	"claude-opus-4":     {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.5},
+	"claude-sonnet-4":   {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3},
+	"claude-haiku-4":    {Input: 1, Output: 5, CacheWrite: 1.25, CacheRead: 0.1},
+	"claude-3-7-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3},
+	"claude-3-5-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3},
+	"claude-3-5-haiku":  {Input: 0.8, Output: 4, CacheWrite: 1, CacheRead: 0.08},
+	"claude-3-opus":     {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.5},
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25, CacheWrite: 0.3, CacheRead: 0.03},
+	"gpt-4o":            {Input: 2.5, Output: 10},
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.6},
+	"gpt-4.1":           {Input: 2, Output: 8},
+	"gpt-4.1-mini":      {Input: 0.4, Output: 1.6},
+}
+
+// PriceFor returns the price of the model, matching the longest known prefix.
+// Unknown models are free, which is only correct for local backends, see Priced.
+func PriceFor(model string) Price {
+	return prices[pricePrefix(model)]
+}
+
+// Priced reports whether the price of the model is known
+func Priced(model string) bool {
+	return pricePrefix(model) != ""
+}
+
+// pricePrefix returns the longest known prefix of the model, empty when there is none
+func pricePrefix(model string) string {
+	var best string
+	for prefix := range prices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	return best
+}
+
+// Cost returns the USD cost of the usage for the model
+func Cost(model string, usage Usage) float64 {
+	price := PriceFor(model)
+	return (float64(usage.InputTokens)*price.Input +
+		float64(usage.OutputTokens)*price.Output +
+		float64(usage.CacheWriteTokens)*price.CacheWrite +
+		float64(usage.CacheReadTokens)*price.CacheRead) / 1_000_000
+}
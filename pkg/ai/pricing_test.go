@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPriceForMatchesLongestPrefix(t *testing.T) {
+	price := PriceFor("claude-3-5-haiku-20241022")
+	if price.Input != 0.8 || price.Output != 4 {
+		t.Errorf("Expected haiku price, got: %+v", price)
+	}
+
+	price = PriceFor("claude-sonnet-4-5")
+	if price.Input != 3 || price.Output != 15 {
+		t.Errorf("Expected sonnet price, got: %+v", price)
+	}
+}
+
+func TestPriced(t *testing.T) {
+	if !Priced("gpt-4o-2024-08-06") {
+		t.Error("Expected a dated model to be priced")
+	}
+	if Priced("claude-sonet-4-5") || Priced("llama3") {
+		t.Error("Expected unknown models not to be priced")
+	}
+}
+
+func TestCost(t *testing.T) {
+	cost := Cost("claude-sonnet-4-5", Usage{InputTokens: 1000, OutputTokens: 500})
+	expected := 0.003 + 0.0075
+	if math.Abs(cost-expected) > 1e-9 {
+		t.Errorf("Expected %f, got: %f", expected, cost)
+	}
+
+	if cost := Cost("llama3", Usage{InputTokens: 1000, OutputTokens: 500}); cost != 0 {
+		t.Errorf("Expected unknown model to be free, got: %f", cost)
+	}
+}
+
+func TestCostWithCache(t *testing.T) {
+	cost := Cost("claude-sonnet-4-5", Usage{
+		InputTokens:      100,
+		OutputTokens:     100,
+		CacheWriteTokens: 1000,
+		CacheReadTokens:  10000,
+	})
+	expected := 0.0003 + 0.0015 + 0.00375 + 0.003
+	if math.Abs(cost-expected) > 1e-9 {
+		t.Errorf("Expected %f, got: %f", expected, cost)
+	}
+}
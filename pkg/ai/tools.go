@@ -0,0 +1,204 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// lastRoundNote is added to the tool results of the final allowed round
+const lastRoundNote = "Tool call limit reached. Answer with the information you have."
+
+// ToolSpec describes a function the model may call
+type ToolSpec struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	// Parameters is the JSON schema of the input object
+	Parameters json.RawMessage `json:"parameters"`
+}
+
+// ToolCall is a function call requested by the model
+type ToolCall struct {
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+}
+
+// ToolResult is the output of a tool call sent back to the model
+type ToolResult struct {
+	CallID  string `json:"call_id"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	IsError bool   `json:"is_error,omitempty"`
+}
+
+// Toolbox runs the functions the model may call
+type Toolbox interface {
+	// Specs describes the available functions
+	Specs() []ToolSpec
+
+	// Call runs the named function with the JSON input and returns its output
+	Call(ctx context.Context, name string, input json.RawMessage) (string, error)
+}
+
+// ToolLoop lets the model call the functions of a toolbox while answering.
+// Each round the model asks for calls, they are run and their results sent back,
+// until it answers without calls or maxRounds rounds of calls have run.
+type ToolLoop struct {
+	tool      Tool
+	toolbox   Toolbox
+	maxRounds int
+}
+
+// NewToolLoop wraps the tool so requests can use the functions of the toolbox
+func NewToolLoop(tool Tool, toolbox Toolbox, maxRounds int) *ToolLoop {
+	return &ToolLoop{
+		tool:      tool,
+		toolbox:   toolbox,
+		maxRounds: maxRounds,
+	}
+}
+
+// Complete runs the tool loop and returns the final reply with the usage of all rounds
+func (l *ToolLoop) Complete(ctx context.Context, req Request) (*Response, error) {
+	req = l.withTools(req)
+	total := &Response{}
+
+	for round := 0; ; round++ {
+		resp, err := l.tool.Complete(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		total = combine(total, resp)
+
+		if len(resp.ToolCalls) == 0 || round >= l.maxRounds {
+			return total, nil
+		}
+		req = l.next(ctx, req, resp, round, nil)
+	}
+}
+
+// Stream runs the tool loop, emitting the text of every round and an event for each tool call.
+// The final event carries the last reply with the usage of all rounds.
+func (l *ToolLoop) Stream(ctx context.Context, req Request) (<-chan StreamEvent, error) {
+	req = l.withTools(req)
+
+	upstream, err := l.tool.Stream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+
+		send := func(event StreamEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		total := &Response{}
+		for round := 0; ; round++ {
+			var resp *Response
+			for event := range upstream {
+				if event.Response != nil {
+					resp = event.Response
+					continue
+				}
+				if event.Partial != nil {
+					// Earlier rounds are billed too
+					event.Partial = combine(total, event.Partial)
+				}
+				if !send(event) {
+					return
+				}
+			}
+			if resp == nil {
+				// The round failed and its error was forwarded
+				return
+			}
+			total = combine(total, resp)
+
+			if len(resp.ToolCalls) == 0 || round >= l.maxRounds {
+				send(StreamEvent{Response: total})
+				return
+			}
+
+			if !send(StreamEvent{Partial: total}) {
+				return
+			}
+			req = l.next(ctx, req, resp, round, send)
+			if ctx.Err() != nil {
+				return
+			}
+
+			upstream, err = l.tool.Stream(ctx, req)
+			if err != nil {
+				send(StreamEvent{Err: err})
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// CurrentCost returns the total cost of the wrapped tool
+func (l *ToolLoop) CurrentCost() float64 {
+	return l.tool.CurrentCost()
+}
+
+func (l *ToolLoop) withTools(req Request) Request {
+	req.Tools = append(append([]ToolSpec(nil), req.Tools...), l.toolbox.Specs()...)
+	req.Messages = append([]Message(nil), req.Messages...)
+	return req
+}
+
+// next runs the tool calls of the reply and returns the request for the following round.
+// Each call is announced through send before it runs when streaming.
+func (l *ToolLoop) next(ctx context.Context, req Request, resp *Response, round int, send func(StreamEvent) bool) Request {
+	results := make([]ToolResult, len(resp.ToolCalls))
+	for i, call := range resp.ToolCalls {
+		if send != nil {
+			send(StreamEvent{ToolCall: &call})
+		}
+
+		output, err := l.toolbox.Call(ctx, call.Name, call.Input)
+		results[i] = ToolResult{CallID: call.ID, Name: call.Name, Content: output}
+		if err != nil {
+			results[i].Content = fmt.Sprintf("error: %v", err)
+			results[i].IsError = true
+		}
+	}
+	if round+1 >= l.maxRounds {
+		results[len(results)-1].Content += "\n\n" + lastRoundNote
+	}
+
+	req.Messages = append(req.Messages,
+		Message{Role: "assistant", Content: resp.Text, ToolCalls: resp.ToolCalls},
+		Message{Role: "user", ToolResults: results},
+	)
+	return req
+}
+
+// combine adds the usage and cost of a round to the running total and takes its reply
+func combine(total *Response, resp *Response) *Response {
+	return &Response{
+		Text:      resp.Text,
+		Model:     resp.Model,
+		ToolCalls: resp.ToolCalls,
+		Route:     resp.Route,
+		Usage: Usage{
+			InputTokens:      total.Usage.InputTokens + resp.Usage.InputTokens,
+			OutputTokens:     total.Usage.OutputTokens + resp.Usage.OutputTokens,
+			CacheWriteTokens: total.Usage.CacheWriteTokens + resp.Usage.CacheWriteTokens,
+			CacheReadTokens:  total.Usage.CacheReadTokens + resp.Usage.CacheReadTokens,
+		},
+		Cost: total.Cost + resp.Cost,
+	}
+}
@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// callingTool asks for a tool call every round until it sees a result for each of its calls
+type callingTool struct {
+	Tool
+	calls    int
+	requests []Request
+}
+
+func (c *callingTool) Complete(ctx context.Context, req Request) (*Response, error) {
+	c.requests = append(c.requests, req)
+	if len(req.Messages) > 2*c.calls {
+		return &Response{Text: "done", Model: "fake", Cost: 0.01, Usage: Usage{InputTokens: 10}}, nil
+	}
+	return &Response{
+		Model:     "fake",
+		Cost:      0.01,
+		Usage:     Usage{InputTokens: 10},
+		ToolCalls: []ToolCall{{ID: "call", Name: "echo", Input: json.RawMessage(`{"text":"hi"}`)}},
+	}, nil
+}
+
+func (c *callingTool) Stream(ctx context.Context, req Request) (<-chan StreamEvent, error) {
+	resp, err := c.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan StreamEvent, 2)
+	if resp.Text != "" {
+		events <- StreamEvent{Delta: resp.Text}
+	}
+	events <- StreamEvent{Response: resp}
+	close(events)
+	return events, nil
+}
+
+type echoBox struct {
+	fail bool
+}
+
+func (e echoBox) Specs() []ToolSpec {
+	return []ToolSpec{{Name: "echo", Description: "echoes", Parameters: json.RawMessage(`{"type":"object"}`)}}
+}
+
+func (e echoBox) Call(ctx context.Context, name string, input json.RawMessage) (string, error) {
+	if e.fail {
+		return "", errors.New("broken")
+	}
+	return string(input), nil
+}
+
+func TestToolLoopRunsCalls(t *testing.T) {
+	tool := &callingTool{calls: 2}
+	loop := NewToolLoop(tool, echoBox{}, 4)
+
+	resp, err := loop.Complete(context.Background(), Request{Messages: []Message{{Role: "user", Content: "go"}}})
+	if err != nil {
+		t.Fatalf("Complete() error: %v", err)
+	}
+
+	if resp.Text != "done" || len(tool.requests) != 3 {
+		t.Errorf("Expected the reply after 2 rounds of calls, got %q after %d requests", resp.Text, len(tool.requests))
+	}
+	if resp.Usage.InputTokens != 30 || resp.Cost < 0.0299 {
+		t.Errorf("Expected the usage of all rounds, got: %+v", resp)
+	}
+
+	last := tool.requests[2]
+	if len(last.Tools) != 1 || len(last.Messages) != 5 {
+		t.Fatalf("Expected tools and 2 rounds of messages, got: %+v", last)
+	}
+	if result := last.Messages[2].ToolResults[0]; result.CallID != "call" || result.Content != `{"text":"hi"}` {
+		t.Errorf("Unexpected tool result: %+v", result)
+	}
+}
+
+func TestToolLoopStopsAtMaxRounds(t *testing.T) {
+	tool := &callingTool{calls: 10}
+	loop := NewToolLoop(tool, echoBox{fail: true}, 2)
+
+	events, err := loop.Stream(context.Background(), Request{Messages: []Message{{Role: "user", Content: "go"}}})
+	if err != nil {
+		t.Fatalf("Stream() error: %v", err)
+	}
+
+	var calls int
+	var final *Response
+	for event := range events {
+		if event.ToolCall != nil {
+			calls++
+		}
+		if event.Response != nil {
+			final = event.Response
+		}
+	}
+
+	if calls != 2 || len(tool.requests) != 3 || final == nil {
+		t.Fatalf("Expected 2 announced calls and 3 requests, got %d and %d", calls, len(tool.requests))
+	}
+
+	result := tool.requests[2].Messages[4].ToolResults[0]
+	if !result.IsError || !strings.HasPrefix(result.Content, "error: broken") || !strings.Contains(result.Content, lastRoundNote) {
+		t.Errorf("Expected the error and the limit note in the last result, got: %+v", result)
+	}
+}
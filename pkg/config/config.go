@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -11,7 +12,33 @@ import (
 type Config struct {
 	WhisperCppPath   string
 	WhisperModelPath string
-	AnthropicApiKey  string // Optional: only needed when using real AI client
+	Transcriber      string  // Optional: stream runs whisper-stream, server posts audio to a whisper.cpp server
+	WhisperServerURL string  // Optional: whisper.cpp server base URL, defaults to http://127.0.0.1:8080
+	ChunkSeconds     float64 // Seconds of audio per whisper.cpp server request, defaults to 5
+	AudioDevice      string  // Optional: capture device index or name, defaults to the system input
+	AIProvider       string  // Optional: anthropic, openai, ollama or replay
+	AIModel          string  // Optional: defaults to the provider's model
+	AISmallModel     string  // Optional: cheap model of the same provider answering first, escalating to AIModel
+	AIBaseURL        string  // Optional: defaults to the provider's URL
+	Cassette         string  // Optional: JSON file real providers record to and replay plays back
+	AnthropicApiKey  string  // Optional: only needed when using real AI client
+	OpenAIApiKey     string  // Optional: not needed for local OpenAI compatible servers
+	BudgetSession    float64 // Optional: USD cap per session, 0 means no cap
+	BudgetDay        float64 // Optional: USD cap per calendar day, 0 means no cap
+	RequestTimeout   float64 // Seconds per AI request including retries
+	ContextWindow    int     // Optional: model context window in tokens, defaults by model
+	Trigger          string  // Optional: heuristic, llm or always
+	PromptsDir       string  // Directory of persona templates
+	Persona          string  // Optional: active persona, defaults to the default persona
+	InFlightPolicy   string  // Optional: cancel, queue or parallel when new transcript arrives during a request
+	ExtractInterval  float64 // Seconds between action item extractions, 0 means on demand only
+	SessionsDir      string  // Directory session records are saved in
+	KnowledgeDir     string  // Optional: directory of documents answers are grounded in
+	KnowledgeTopK    int     // Optional: passages added to each request, defaults to 3
+	ToolDepth        int     // Rounds of tool calls allowed per answer, 0 disables tools
+	RepoDir          string  // Optional: repository the model may read files from
+	Redact           bool    // Mask personal data and secrets before text is sent to the AI backend
+	RedactPatterns   string  // Optional: file of extra regular expressions to mask, one per line
 	BufferTimeout    float64
 	Debug            bool
 }
@@ -21,24 +48,209 @@ func LoadConfig() (*Config, error) {
 	// Load .env file if it exists
 	_ = godotenv.Load()
 
+	transcriber := os.Getenv("TRANSCRIBER")
+	if transcriber == "" {
+		transcriber = "stream"
+	}
+	switch transcriber {
+	case "stream", "server":
+	default:
+		return nil, fmt.Errorf("TRANSCRIBER must be one of stream or server, got %q", transcriber)
+	}
+
+	// The server backend talks to a running whisper.cpp server, which has its own model
 	whisperPath := os.Getenv("WHISPER_CPP_PATH")
-	if whisperPath == "" {
+	if whisperPath == "" && transcriber == "stream" {
 		return nil, fmt.Errorf("WHISPER_CPP_PATH environment variable not set")
 	}
 
 	modelPath := os.Getenv("WHISPER_MODEL_PATH")
-	if modelPath == "" {
+	if modelPath == "" && transcriber == "stream" {
 		return nil, fmt.Errorf("WHISPER_MODEL_PATH environment variable not set")
 	}
 
+	serverURL := os.Getenv("WHISPER_SERVER_URL")
+	if serverURL == "" {
+		serverURL = "http://127.0.0.1:8080"
+	}
+
+	chunkSeconds, err := getEnvFloat("WHISPER_CHUNK_SECONDS")
+	if err != nil {
+		return nil, err
+	}
+	if chunkSeconds == 0 {
+		chunkSeconds = 5
+	}
+
 	// API key is now optional
 	apiKey := os.Getenv("ANTHROPIC_API_KEY")
 
+	provider := os.Getenv("AI_PROVIDER")
+	if provider == "" {
+		provider = "anthropic"
+		if apiKey == "" {
+			provider = "replay"
+		}
+	}
+	switch provider {
+	case "anthropic", "openai", "ollama", "replay":
+	default:
+		return nil, fmt.Errorf("AI_PROVIDER must be one of anthropic, openai, ollama or replay, got %q", provider)
+	}
+
+	// ANTHROPIC_MODEL predates AI_MODEL and only names Anthropic models
+	model := os.Getenv("AI_MODEL")
+	if model == "" && provider == "anthropic" {
+		model = os.Getenv("ANTHROPIC_MODEL")
+	}
+
+	budgetSession, err := getEnvFloat("AI_BUDGET_SESSION_USD")
+	if err != nil {
+		return nil, err
+	}
+
+	budgetDay, err := getEnvFloat("AI_BUDGET_DAY_USD")
+	if err != nil {
+		return nil, err
+	}
+
+	requestTimeout, err := getEnvFloat("AI_REQUEST_TIMEOUT")
+	if err != nil {
+		return nil, err
+	}
+	if requestTimeout == 0 {
+		requestTimeout = 60
+	}
+
+	contextWindow := 0
+	if value := os.Getenv("AI_CONTEXT_WINDOW"); value != "" {
+		contextWindow, err = strconv.Atoi(value)
+		if err != nil || contextWindow <= 0 {
+			return nil, fmt.Errorf("AI_CONTEXT_WINDOW must be a positive number of tokens, got %q", value)
+		}
+	}
+
+	trigger := os.Getenv("AI_TRIGGER")
+	if trigger == "" {
+		trigger = "heuristic"
+	}
+	switch trigger {
+	case "heuristic", "llm", "always":
+	default:
+		return nil, fmt.Errorf("AI_TRIGGER must be one of heuristic, llm or always, got %q", trigger)
+	}
+
+	inFlightPolicy := os.Getenv("AI_INFLIGHT_POLICY")
+	if inFlightPolicy == "" {
+		inFlightPolicy = "cancel"
+	}
+	switch inFlightPolicy {
+	case "cancel", "queue", "parallel":
+	default:
+		return nil, fmt.Errorf("AI_INFLIGHT_POLICY must be one of cancel, queue or parallel, got %q", inFlightPolicy)
+	}
+
+	extractInterval, err := getEnvFloat("AI_EXTRACT_INTERVAL")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := os.LookupEnv("AI_EXTRACT_INTERVAL"); !ok {
+		extractInterval = 120
+	}
+
+	sessionsDir := os.Getenv("SESSIONS_DIR")
+	if sessionsDir == "" {
+		sessionsDir = "sessions"
+	}
+
+	knowledgeTopK, err := getEnvFloat("KNOWLEDGE_TOP_K")
+	if err != nil {
+		return nil, err
+	}
+
+	toolDepth, err := getEnvFloat("AI_TOOL_DEPTH")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := os.LookupEnv("AI_TOOL_DEPTH"); !ok {
+		toolDepth = 4
+	}
+
+	redact := true
+	if value := os.Getenv("REDACT"); value != "" {
+		redact, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("REDACT must be true or false: %w", err)
+		}
+	}
+
+	promptsDir := os.Getenv("PROMPTS_DIR")
+	if promptsDir == "" {
+		promptsDir = "prompts"
+	}
+
 	return &Config{
 		WhisperCppPath:   whisperPath,
 		WhisperModelPath: modelPath,
+		Transcriber:      transcriber,
+		WhisperServerURL: serverURL,
+		ChunkSeconds:     chunkSeconds,
+		AudioDevice:      os.Getenv("AUDIO_DEVICE"),
+		AIProvider:       provider,
+		AIModel:          model,
+		AISmallModel:     os.Getenv("AI_SMALL_MODEL"),
+		AIBaseURL:        os.Getenv("AI_BASE_URL"),
+		Cassette:         os.Getenv("AI_CASSETTE"),
 		AnthropicApiKey:  apiKey,
+		OpenAIApiKey:     os.Getenv("OPENAI_API_KEY"),
+		BudgetSession:    budgetSession,
+		BudgetDay:        budgetDay,
+		RequestTimeout:   requestTimeout,
+		ContextWindow:    contextWindow,
+		Trigger:          trigger,
+		PromptsDir:       promptsDir,
+		Persona:          os.Getenv("AI_PERSONA"),
+		InFlightPolicy:   inFlightPolicy,
+		ExtractInterval:  extractInterval,
+		SessionsDir:      sessionsDir,
+		KnowledgeDir:     os.Getenv("KNOWLEDGE_DIR"),
+		KnowledgeTopK:    int(knowledgeTopK),
+		ToolDepth:        int(toolDepth),
+		RepoDir:          os.Getenv("REPO_DIR"),
+		Redact:           redact,
+		RedactPatterns:   os.Getenv("REDACT_PATTERNS_FILE"),
 		BufferTimeout:    1.0, // Default value
 		Debug:            false,
 	}, nil
 }
+
+// String formats the config for logs with the API keys masked
+func (c Config) String() string {
+	type plain Config
+	masked := plain(c)
+	masked.AnthropicApiKey = mask(c.AnthropicApiKey)
+	masked.OpenAIApiKey = mask(c.OpenAIApiKey)
+	return fmt.Sprintf("%+v", masked)
+}
+
+// mask hides a secret, showing only whether it is set
+func mask(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "***"
+}
+
+// getEnvFloat parses an optional numeric environment variable, returning 0 when unset
+func getEnvFloat(key string) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number: %w", key, err)
+	}
+	return f, nil
+}
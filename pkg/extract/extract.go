@@ -0,0 +1,327 @@
+package extract
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dimitarkovachev/eng-assist/pkg/ai"
+	"github.com/dimitarkovachev/eng-assist/pkg/state"
+)
+
+// dateLayout is the format of action item due dates
+const dateLayout = "2006-01-02"
+
+const (
+	// maxChunkTokens bounds the transcript sent in one request even for very large context windows
+	maxChunkTokens = 20000
+
+	// minChunkTokens keeps chunks useful for small local models
+	minChunkTokens = 1000
+)
+
+const extractPrompt = `You extract structured notes from the transcript of a live engineering conversation.
+Reply with a single JSON object and nothing else, matching this schema:
+{
+  "action_items": [{"task": "what needs to be done", "owner": "who does it, or empty", "due": "YYYY-MM-DD, or empty"}],
+  "decisions": [{"decision": "what was decided"}],
+  "open_questions": ["question raised and not yet answered"]
+}
+Only include items stated in the transcript. Use empty arrays when there are none.
+Resolve relative due dates against today, which is %s.`
+
+// ErrNoTranscript is returned when there is nothing to extract from
+var ErrNoTranscript = errors.New("no transcript to extract from")
+
+// ErrReset is returned when the session was reset while an extraction was running
+var ErrReset = errors.New("session was reset during extraction")
+
+// ActionItem is a task someone agreed to do
+type ActionItem struct {
+	Task  string `json:"task"`
+	Owner string `json:"owner,omitempty"`
+	Due   string `json:"due,omitempty"`
+}
+
+// Decision is something the conversation settled
+type Decision struct {
+	Decision string `json:"decision"`
+}
+
+// Result holds the records extracted from a transcript
+type Result struct {
+	ActionItems   []ActionItem `json:"action_items"`
+	Decisions     []Decision   `json:"decisions"`
+	OpenQuestions []string     `json:"open_questions"`
+}
+
+// Parse decodes the model reply into a result and validates it.
+// Markdown code fences and text around the JSON object are ignored.
+func Parse(reply string) (*Result, error) {
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("reply contains no JSON object")
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(reply[start : end+1]))
+	decoder.DisallowUnknownFields()
+
+	var result Result
+	if err := decoder.Decode(&result); err != nil {
+		return nil, fmt.Errorf("reply does not match the schema: %w", err)
+	}
+
+	if err := result.Validate(); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Validate checks that every record is filled in and due dates are dates
+func (r *Result) Validate() error {
+	var errs []error
+	for i, item := range r.ActionItems {
+		if strings.TrimSpace(item.Task) == "" {
+			errs = append(errs, fmt.Errorf("action_items[%d]: task is empty", i))
+		}
+		if item.Due != "" {
+			if _, err := time.Parse(dateLayout, item.Due); err != nil {
+				errs = append(errs, fmt.Errorf("action_items[%d]: due %q is not YYYY-MM-DD", i, item.Due))
+			}
+		}
+	}
+	for i, decision := range r.Decisions {
+		if strings.TrimSpace(decision.Decision) == "" {
+			errs = append(errs, fmt.Errorf("decisions[%d]: decision is empty", i))
+		}
+	}
+	for i, question := range r.OpenQuestions {
+		if strings.TrimSpace(question) == "" {
+			errs = append(errs, fmt.Errorf("open_questions[%d]: question is empty", i))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Extractor asks the model for action items, decisions and open questions in the transcript
+// and merges them into the session store. Each run only reads the final segments added since
+// the last one, split into chunks that fit the context window.
+type Extractor struct {
+	tool        ai.Tool
+	appState    *state.AppState
+	store       *Store
+	chunkTokens int
+	logger      *log.Logger
+
+	// mu lets one extraction run at a time
+	mu sync.Mutex
+
+	// sessionMu guards extracted and session, which Reset changes while an extraction may be running
+	sessionMu sync.Mutex
+	extracted map[segmentKey]bool
+	session   int
+}
+
+// segmentKey identifies a transcript segment across revisions of the transcript
+type segmentKey struct {
+	source state.Source
+	time   time.Time
+}
+
+// New creates an extractor for a model with the given context window
+func New(tool ai.Tool, appState *state.AppState, store *Store, contextWindow int, logger *log.Logger) *Extractor {
+	return &Extractor{
+		tool:        tool,
+		appState:    appState,
+		store:       store,
+		chunkTokens: min(max(contextWindow/4, minChunkTokens), maxChunkTokens),
+		logger:      logger,
+		extracted:   make(map[segmentKey]bool),
+	}
+}
+
+// Store returns the store extracted records are kept in
+func (e *Extractor) Store() *Store {
+	return e.store
+}
+
+// Reset starts a new session saving to path. Records of an extraction still running
+// for the old session are dropped instead of merged into the new one.
+func (e *Extractor) Reset(path string) {
+	e.sessionMu.Lock()
+	defer e.sessionMu.Unlock()
+
+	e.session++
+	e.extracted = make(map[segmentKey]bool)
+	e.store.Open(path)
+}
+
+// Run extracts from the transcript every interval until the context is cancelled.
+// Runs are skipped while paused or when nothing was said since the last one.
+func (e *Extractor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, pending := e.pending()
+			if e.appState.IsPaused() || len(pending) == 0 {
+				continue
+			}
+
+			if _, err := e.Extract(ctx); err != nil && ctx.Err() == nil && !errors.Is(err, ErrReset) {
+				e.logger.Printf("Error extracting action items: %v", err)
+			}
+		}
+	}
+}
+
+// Extract runs an extraction over the transcript said since the last run and returns what it found.
+// Chunks are merged into the store as they are done, so a failed chunk is retried on the next run.
+func (e *Extractor) Extract(ctx context.Context) (*Result, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.appState.TranscriptState.Segments()) == 0 {
+		return nil, ErrNoTranscript
+	}
+
+	session, pending := e.pending()
+	found := &Result{}
+	for _, chunk := range e.chunks(pending) {
+		lines := make([]string, len(chunk))
+		for i, segment := range chunk {
+			lines[i] = segment.Text
+		}
+
+		result, err := e.extract(ctx, strings.Join(lines, "\n"))
+		if err != nil {
+			return nil, err
+		}
+		if err := e.merge(session, chunk, *result); err != nil {
+			return nil, err
+		}
+		found.ActionItems = append(found.ActionItems, result.ActionItems...)
+		found.Decisions = append(found.Decisions, result.Decisions...)
+		found.OpenQuestions = append(found.OpenQuestions, result.OpenQuestions...)
+	}
+	return found, nil
+}
+
+// pending returns the current session and its final segments not extracted yet, in transcript order
+func (e *Extractor) pending() (int, []state.Segment) {
+	e.sessionMu.Lock()
+	defer e.sessionMu.Unlock()
+
+	var pending []state.Segment
+	for _, segment := range e.appState.TranscriptState.Segments() {
+		if segment.Final && segment.Text != "" && !e.extracted[segmentKey{segment.Source, segment.Time}] {
+			pending = append(pending, segment)
+		}
+	}
+	return e.session, pending
+}
+
+// merge adds the records of a chunk to the store and marks its segments as extracted,
+// unless the session was reset since the chunk was read
+func (e *Extractor) merge(session int, chunk []state.Segment, result Result) error {
+	e.sessionMu.Lock()
+	defer e.sessionMu.Unlock()
+
+	if session != e.session {
+		return ErrReset
+	}
+	if err := e.store.Merge(result); err != nil {
+		return err
+	}
+	for _, segment := range chunk {
+		e.extracted[segmentKey{segment.Source, segment.Time}] = true
+	}
+	return nil
+}
+
+// chunks groups the segments into chunks of at most chunkTokens.
+// A segment longer than that gets a chunk of its own.
+func (e *Extractor) chunks(segments []state.Segment) [][]state.Segment {
+	var chunks [][]state.Segment
+	var chunk []state.Segment
+	tokens := 0
+	for _, segment := range segments {
+		size := ai.EstimateTokens(segment.Text + "\n")
+		if len(chunk) > 0 && tokens+size > e.chunkTokens {
+			chunks = append(chunks, chunk)
+			chunk, tokens = nil, 0
+		}
+		chunk = append(chunk, segment)
+		tokens += size
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// extract asks the model for the records in one chunk of transcript.
+// An invalid reply is sent back to the model once with the validation error.
+func (e *Extractor) extract(ctx context.Context, transcript string) (*Result, error) {
+	req := ai.Request{
+		System:    fmt.Sprintf(extractPrompt, time.Now().Format(dateLayout)),
+		Messages:  []ai.Message{{Role: "user", Content: transcript}},
+		MaxTokens: 2048,
+	}
+
+	result, err := e.complete(ctx, &req)
+	if err != nil {
+		if ctx.Err() != nil || len(req.Messages) == 1 {
+			return nil, err
+		}
+		e.logger.Printf("Retrying invalid extraction: %v", err)
+		if result, err = e.complete(ctx, &req); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// complete sends the request and parses the reply.
+// When the reply is invalid it is added to the request together with the error, ready for a retry.
+func (e *Extractor) complete(ctx context.Context, req *ai.Request) (*Result, error) {
+	resp, err := e.tool.Complete(ctx, *req)
+	if err != nil {
+		return nil, err
+	}
+	e.record(resp)
+
+	result, err := Parse(resp.Text)
+	if err != nil {
+		req.Messages = append(req.Messages,
+			ai.Message{Role: "assistant", Content: resp.Text},
+			ai.Message{Role: "user", Content: fmt.Sprintf("That reply was invalid: %v. Reply with the corrected JSON object only.", err)},
+		)
+		return nil, err
+	}
+	return result, nil
+}
+
+// record adds the usage of the response to the cost ledger
+func (e *Extractor) record(resp *ai.Response) {
+	e.appState.AddCost(state.CostEntry{
+		Model:            resp.Model,
+		InputTokens:      resp.Usage.InputTokens,
+		OutputTokens:     resp.Usage.OutputTokens,
+		CacheWriteTokens: resp.Usage.CacheWriteTokens,
+		CacheReadTokens:  resp.Usage.CacheReadTokens,
+		Cost:             resp.Cost,
+		Timestamp:        time.Now(),
+	})
+}
@@ -0,0 +1,185 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/dimitarkovachev/eng-assist/pkg/ai"
+	"github.com/dimitarkovachev/eng-assist/pkg/state"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		valid bool
+	}{
+		{"plain", `{"action_items":[{"task":"Rotate the keys","owner":"Ana","due":"2026-10-20"}],"decisions":[{"decision":"Ship on Monday"}],"open_questions":["Who owns billing?"]}`, true},
+		{"fenced", "```json\n{\"action_items\":[],\"decisions\":[],\"open_questions\":[]}\n```", true},
+		{"optional fields", `{"action_items":[{"task":"Write the postmortem"}]}`, true},
+		{"no json", "There were no action items.", false},
+		{"unknown field", `{"actions":[{"task":"x"}]}`, false},
+		{"empty task", `{"action_items":[{"task":" ","owner":"Ana"}]}`, false},
+		{"bad due date", `{"action_items":[{"task":"Deploy","due":"next Friday"}]}`, false},
+		{"empty decision", `{"decisions":[{"decision":""}]}`, false},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.reply)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: Parse() error = %v, expected valid = %t", tt.name, err, tt.valid)
+		}
+	}
+}
+
+// scriptedTool replies with the given texts in order
+type scriptedTool struct {
+	ai.Tool
+	replies  []string
+	requests []ai.Request
+
+	// onComplete runs before each reply when set
+	onComplete func()
+}
+
+func (s *scriptedTool) Complete(ctx context.Context, req ai.Request) (*ai.Response, error) {
+	s.requests = append(s.requests, req)
+	if s.onComplete != nil {
+		s.onComplete()
+	}
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+	return &ai.Response{Text: reply, Model: "scripted", Cost: 0.01}, nil
+}
+
+func TestExtractRetriesInvalidReply(t *testing.T) {
+	tool := &scriptedTool{replies: []string{
+		`{"action_items":[{"task":"Rotate the keys","due":"tomorrow"}]}`,
+		`{"action_items":[{"task":"Rotate the keys","owner":"Ana","due":"2026-10-18"}],"decisions":[{"decision":"Freeze deploys"}]}`,
+	}}
+	appState := state.NewAppState()
+	appState.TranscriptState.Write("Ana will rotate the keys tomorrow. We freeze deploys.")
+	store := NewStore("")
+	extractor := New(tool, appState, store, 0, log.New(io.Discard, "", 0))
+
+	result, err := extractor.Extract(context.Background())
+	if err != nil {
+		t.Fatalf("Extract() error: %v", err)
+	}
+
+	if len(tool.requests) != 2 {
+		t.Fatalf("Expected one retry, got %d requests", len(tool.requests))
+	}
+	retry := tool.requests[1].Messages
+	if len(retry) != 3 || !strings.Contains(retry[2].Content, "not YYYY-MM-DD") {
+		t.Errorf("Expected the validation error in the retry, got: %+v", retry)
+	}
+
+	if len(result.ActionItems) != 1 || result.ActionItems[0].Owner != "Ana" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if got := store.Result(); len(got.Decisions) != 1 {
+		t.Errorf("Expected the result merged into the store, got: %+v", got)
+	}
+	if len(appState.Ledger()) != 2 {
+		t.Errorf("Expected both requests in the ledger, got: %d", len(appState.Ledger()))
+	}
+}
+
+func TestExtractWithoutTranscript(t *testing.T) {
+	extractor := New(&scriptedTool{}, state.NewAppState(), NewStore(""), 0, log.New(io.Discard, "", 0))
+
+	if _, err := extractor.Extract(context.Background()); err != ErrNoTranscript {
+		t.Errorf("Expected ErrNoTranscript, got: %v", err)
+	}
+}
+
+func TestExtractReadsNewTranscriptInChunks(t *testing.T) {
+	empty := `{"action_items":[],"decisions":[],"open_questions":[]}`
+	tool := &scriptedTool{replies: []string{
+		`{"action_items":[{"task":"Rotate the keys","owner":"Ana"}]}`,
+		empty,
+		`{"decisions":[{"decision":"Freeze deploys"}]}`,
+	}}
+	appState := state.NewAppState()
+	appState.TranscriptState.Write("Ana will rotate the keys.")
+	// Longer than the word limit of the string API and than one chunk
+	for i := 0; i < 40; i++ {
+		appState.TranscriptState.Write(strings.Repeat("we talked about the rollout plan ", 5))
+	}
+	store := NewStore("")
+	extractor := New(tool, appState, store, 0, log.New(io.Discard, "", 0))
+
+	if _, err := extractor.Extract(context.Background()); err != nil {
+		t.Fatalf("Extract() error: %v", err)
+	}
+	if len(tool.requests) != 2 {
+		t.Fatalf("Expected the transcript in 2 chunks, got %d requests", len(tool.requests))
+	}
+	if first := tool.requests[0].Messages[0].Content; !strings.HasPrefix(first, "Ana will rotate the keys.") {
+		t.Errorf("Expected the start of the transcript in the first chunk, got: %.40q", first)
+	}
+
+	appState.TranscriptState.Write("We freeze deploys.")
+	if _, err := extractor.Extract(context.Background()); err != nil {
+		t.Fatalf("Extract() error: %v", err)
+	}
+	if got := tool.requests[2].Messages[0].Content; got != "We freeze deploys." {
+		t.Errorf("Expected only the new transcript, got: %q", got)
+	}
+
+	if result, err := extractor.Extract(context.Background()); err != nil || len(tool.requests) != 3 || len(result.ActionItems) != 0 {
+		t.Errorf("Expected nothing to extract, got %+v, %v after %d requests", result, err, len(tool.requests))
+	}
+	if got := store.Result(); len(got.ActionItems) != 1 || len(got.Decisions) != 1 {
+		t.Errorf("Expected records of all runs in the store, got: %+v", got)
+	}
+}
+
+func TestExtractDropsResultsAfterReset(t *testing.T) {
+	reply := `{"action_items":[{"task":"Rotate the keys","owner":"Ana"}]}`
+	tool := &scriptedTool{replies: []string{reply, reply}}
+	appState := state.NewAppState()
+	appState.TranscriptState.Write("Ana will rotate the keys.")
+	store := NewStore("")
+	extractor := New(tool, appState, store, 0, log.New(io.Discard, "", 0))
+
+	tool.onComplete = func() { extractor.Reset("") }
+	if _, err := extractor.Extract(context.Background()); !errors.Is(err, ErrReset) {
+		t.Fatalf("Expected ErrReset, got: %v", err)
+	}
+	if got := store.Result(); len(got.ActionItems) != 0 {
+		t.Errorf("Expected nothing merged into the new session, got: %+v", got)
+	}
+
+	tool.onComplete = nil
+	if _, err := extractor.Extract(context.Background()); err != nil {
+		t.Fatalf("Extract() error: %v", err)
+	}
+	if len(tool.requests) != 2 {
+		t.Fatalf("Expected the transcript to be extracted again, got %d requests", len(tool.requests))
+	}
+	if got := store.Result(); len(got.ActionItems) != 1 {
+		t.Errorf("Expected the records in the new session, got: %+v", got)
+	}
+}
+
+func TestResetForgetsExtractedSegments(t *testing.T) {
+	reply := `{"action_items":[],"decisions":[],"open_questions":[]}`
+	tool := &scriptedTool{replies: []string{reply, reply}}
+	appState := state.NewAppState()
+	appState.TranscriptState.Write("We freeze deploys.")
+	extractor := New(tool, appState, NewStore(""), 0, log.New(io.Discard, "", 0))
+
+	if _, err := extractor.Extract(context.Background()); err != nil {
+		t.Fatalf("Extract() error: %v", err)
+	}
+	extractor.Reset("")
+	if _, pending := extractor.pending(); len(pending) != 1 {
+		t.Errorf("Expected the segment to be pending after a reset, got %d", len(pending))
+	}
+}
@@ -0,0 +1,139 @@
+package extract
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Store keeps the records extracted during a session and saves them as JSON next to it.
+// Records found again by later extractions are merged rather than duplicated.
+type Store struct {
+	mu     sync.RWMutex
+	path   string
+	result Result
+}
+
+// NewStore creates an empty store saving to path, an empty path keeps it in memory only
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Open starts a new session saving to path, forgetting the current records
+func (s *Store) Open(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.path = path
+	s.result = Result{}
+}
+
+// Path returns the file the records are saved to
+func (s *Store) Path() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.path
+}
+
+// Result returns a copy of all records
+func (s *Store) Result() Result {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return Result{
+		ActionItems:   append([]ActionItem{}, s.result.ActionItems...),
+		Decisions:     append([]Decision{}, s.result.Decisions...),
+		OpenQuestions: append([]string{}, s.result.OpenQuestions...),
+	}
+}
+
+// Merge adds the new records and saves the store.
+// A known action item gets the owner and due date filled in when the new one has them.
+func (s *Store) Merge(result Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range result.ActionItems {
+		existing := s.findAction(item.Task)
+		if existing == nil {
+			s.result.ActionItems = append(s.result.ActionItems, item)
+			continue
+		}
+		if item.Owner != "" {
+			existing.Owner = item.Owner
+		}
+		if item.Due != "" {
+			existing.Due = item.Due
+		}
+	}
+
+	for _, decision := range result.Decisions {
+		if !s.hasDecision(decision.Decision) {
+			s.result.Decisions = append(s.result.Decisions, decision)
+		}
+	}
+
+	for _, question := range result.OpenQuestions {
+		if !s.hasQuestion(question) {
+			s.result.OpenQuestions = append(s.result.OpenQuestions, question)
+		}
+	}
+
+	return s.save()
+}
+
+func (s *Store) findAction(task string) *ActionItem {
+	for i := range s.result.ActionItems {
+		if normalize(s.result.ActionItems[i].Task) == normalize(task) {
+			return &s.result.ActionItems[i]
+		}
+	}
+	return nil
+}
+
+func (s *Store) hasDecision(decision string) bool {
+	for _, known := range s.result.Decisions {
+		if normalize(known.Decision) == normalize(decision) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) hasQuestion(question string) bool {
+	for _, known := range s.result.OpenQuestions {
+		if normalize(known) == normalize(question) {
+			return true
+		}
+	}
+	return false
+}
+
+// save writes the records once there are any, so empty sessions leave no file behind
+func (s *Store) save() error {
+	empty := len(s.result.ActionItems) == 0 && len(s.result.Decisions) == 0 && len(s.result.OpenQuestions) == 0
+	if s.path == "" || empty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode records: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+	if err := os.WriteFile(s.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to save records: %w", err)
+	}
+	return nil
+}
+
+// normalize makes records that only differ in case, spacing or final punctuation compare equal
+func normalize(text string) string {
+	return strings.TrimRight(strings.ToLower(strings.Join(strings.Fields(text), " ")), ".!?")
+}
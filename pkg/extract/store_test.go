@@ -0,0 +1,68 @@
+package extract
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreMergesDuplicates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session", "actions.json")
+	store := NewStore(path)
+
+	err := store.Merge(Result{
+		ActionItems:   []ActionItem{{Task: "Rotate the keys"}},
+		Decisions:     []Decision{{Decision: "Freeze deploys"}},
+		OpenQuestions: []string{"Who owns billing?"},
+	})
+	if err != nil {
+		t.Fatalf("Merge() error: %v", err)
+	}
+
+	err = store.Merge(Result{
+		ActionItems:   []ActionItem{{Task: "rotate the  keys.", Owner: "Ana"}, {Task: "Update the runbook"}},
+		Decisions:     []Decision{{Decision: "freeze deploys"}},
+		OpenQuestions: []string{"who owns billing"},
+	})
+	if err != nil {
+		t.Fatalf("Merge() error: %v", err)
+	}
+
+	got := store.Result()
+	if len(got.ActionItems) != 2 || got.ActionItems[0].Owner != "Ana" {
+		t.Errorf("Expected the owner merged into the known action item, got: %+v", got.ActionItems)
+	}
+	if len(got.Decisions) != 1 || len(got.OpenQuestions) != 1 {
+		t.Errorf("Expected duplicates to be merged, got: %+v", got)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected the records saved: %v", err)
+	}
+	var saved Result
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("Failed to decode saved records: %v", err)
+	}
+	if len(saved.ActionItems) != 2 {
+		t.Errorf("Expected 2 saved action items, got: %+v", saved)
+	}
+}
+
+func TestStoreOpenStartsNewSession(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(filepath.Join(dir, "first.json"))
+	if err := store.Merge(Result{Decisions: []Decision{{Decision: "Ship it"}}}); err != nil {
+		t.Fatalf("Merge() error: %v", err)
+	}
+
+	store.Open(filepath.Join(dir, "second.json"))
+
+	if got := store.Result(); len(got.Decisions) != 0 {
+		t.Errorf("Expected an empty store, got: %+v", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "second.json")); !os.IsNotExist(err) {
+		t.Error("Expected no file for an empty session")
+	}
+}
@@ -0,0 +1,184 @@
+package knowledge
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// BM25 parameters
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Extensions lists the file types that are indexed
+var Extensions = []string{".md", ".txt", ".go"}
+
+// Passage is an indexed piece of a document
+type Passage struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Text      string `json:"text"`
+}
+
+// Citation returns the path and line range of the passage
+func (p Passage) Citation() string {
+	return fmt.Sprintf("%s:%d-%d", p.Path, p.StartLine, p.EndLine)
+}
+
+// Result is a passage matching a query
+type Result struct {
+	Passage
+	Score float64 `json:"score"`
+}
+
+type posting struct {
+	passage int
+	count   int
+}
+
+// Index is a BM25 inverted index over the passages of a directory
+type Index struct {
+	root      string
+	passages  []Passage
+	lengths   []int
+	avgLength float64
+	postings  map[string][]posting
+}
+
+// Build indexes the Markdown, text and Go files under dir.
+// Hidden directories are skipped.
+func Build(dir string) (*Index, error) {
+	idx := &Index{
+		root:     dir,
+		postings: make(map[string][]posting),
+	}
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !indexed(path) {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		for _, passage := range split(filepath.ToSlash(rel), string(data)) {
+			idx.add(passage)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to index %s: %w", dir, err)
+	}
+
+	total := 0
+	for _, length := range idx.lengths {
+		total += length
+	}
+	if len(idx.lengths) > 0 {
+		idx.avgLength = float64(total) / float64(len(idx.lengths))
+	}
+
+	return idx, nil
+}
+
+func indexed(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, known := range Extensions {
+		if ext == known {
+			return true
+		}
+	}
+	return false
+}
+
+func (idx *Index) add(passage Passage) {
+	id := len(idx.passages)
+	terms := tokenize(passage.Path + " " + passage.Text)
+
+	counts := make(map[string]int)
+	for _, term := range terms {
+		counts[term]++
+	}
+	for term, count := range counts {
+		idx.postings[term] = append(idx.postings[term], posting{passage: id, count: count})
+	}
+
+	idx.passages = append(idx.passages, passage)
+	idx.lengths = append(idx.lengths, len(terms))
+}
+
+// Root returns the indexed directory
+func (idx *Index) Root() string {
+	return idx.root
+}
+
+// Len returns the number of indexed passages
+func (idx *Index) Len() int {
+	return len(idx.passages)
+}
+
+// Search returns up to limit passages ranked by BM25 score against the query.
+// Passages sharing no terms with the query are never returned.
+func (idx *Index) Search(query string, limit int) []Result {
+	n := float64(len(idx.passages))
+	scores := make(map[int]float64)
+
+	seen := make(map[string]bool)
+	for _, term := range tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		postings := idx.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for _, p := range postings {
+			tf := float64(p.count)
+			norm := 1 - b + b*float64(idx.lengths[p.passage])/idx.avgLength
+			scores[p.passage] += idf * tf * (k1 + 1) / (tf + k1*norm)
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, Result{Passage: idx.passages[id], Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Citation() < results[j].Citation()
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
@@ -0,0 +1,93 @@
+package knowledge
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func buildTestIndex(t *testing.T) *Index {
+	t.Helper()
+	idx, err := Build("testdata/docs")
+	if err != nil {
+		t.Fatalf("Build() error: %v", err)
+	}
+	return idx
+}
+
+func TestBuildSkipsHiddenAndUnknownFiles(t *testing.T) {
+	idx := buildTestIndex(t)
+
+	for _, passage := range idx.passages {
+		if strings.HasPrefix(passage.Path, ".git/") || strings.HasSuffix(passage.Path, ".png") {
+			t.Errorf("Expected %s not to be indexed", passage.Path)
+		}
+	}
+	if idx.Len() != 5 {
+		t.Errorf("Expected 5 passages, got: %d", idx.Len())
+	}
+}
+
+func TestSearch(t *testing.T) {
+	idx := buildTestIndex(t)
+
+	tests := []struct {
+		query    string
+		citation string
+	}{
+		{"how do we roll back the deploy?", "runbooks/deploy.md:6-10"},
+		{"why did the cache hit rate drop", "design/cache.md:1-5"},
+		{"what is the context window of the model", "src/window.go:1-6"},
+		{"can we disable it with a feature flag", "runbooks/deploy.md:12-14"},
+	}
+
+	for _, tt := range tests {
+		results := idx.Search(tt.query, 3)
+		if len(results) == 0 {
+			t.Errorf("Search(%q) found nothing", tt.query)
+			continue
+		}
+		if got := results[0].Citation(); got != tt.citation {
+			t.Errorf("Search(%q) top result = %s, expected %s", tt.query, got, tt.citation)
+		}
+	}
+}
+
+func TestSearchWithoutMatches(t *testing.T) {
+	idx := buildTestIndex(t)
+
+	if results := idx.Search("what is for lunch", 3); len(results) != 0 {
+		t.Errorf("Expected no results, got: %+v", results)
+	}
+	if results := idx.Search("deploy", 2); len(results) != 2 {
+		t.Errorf("Expected results limited to 2, got: %d", len(results))
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text  string
+		terms []string
+	}{
+		{"How do we deploy the API?", []string{"deploy", "api"}},
+		{"ContextWindowFor", []string{"contextwindowfor", "context", "window"}},
+		{"max_tokens HTTPServer", []string{"maxtokens", "max", "tokens", "httpserver", "http", "server"}},
+	}
+
+	for _, tt := range tests {
+		if got := tokenize(tt.text); !reflect.DeepEqual(got, tt.terms) {
+			t.Errorf("tokenize(%q) = %v, expected %v", tt.text, got, tt.terms)
+		}
+	}
+}
+
+func TestSplitStartsPassagesAtHeadings(t *testing.T) {
+	passages := split("doc.md", "# Title\n\nintro\n\n## Section\nbody\n\n")
+
+	if len(passages) != 2 {
+		t.Fatalf("Expected 2 passages, got: %+v", passages)
+	}
+	if passages[0].StartLine != 1 || passages[0].EndLine != 3 || passages[1].StartLine != 5 || passages[1].EndLine != 6 {
+		t.Errorf("Unexpected line ranges: %+v", passages)
+	}
+}
@@ -0,0 +1,6 @@
+package src
+
+// ContextWindowFor returns the context window of the model in tokens
+func ContextWindowFor(model string) int {
+	return 200000
+}
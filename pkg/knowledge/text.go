@@ -0,0 +1,111 @@
+package knowledge
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxPassageWords bounds the size of a passage so retrieved context stays focused
+const maxPassageWords = 150
+
+// stopwords are frequent English words that carry no meaning for retrieval
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "but": true,
+	"by": true, "can": true, "do": true, "does": true, "for": true, "from": true, "has": true, "have": true,
+	"how": true, "i": true, "if": true, "in": true, "is": true, "it": true, "its": true, "of": true,
+	"on": true, "or": true, "so": true, "that": true, "the": true, "this": true, "to": true, "was": true,
+	"we": true, "what": true, "when": true, "where": true, "which": true, "who": true, "why": true,
+	"will": true, "with": true, "you": true,
+}
+
+// tokenize lowercases the text and splits it into terms without stopwords.
+// Identifiers also yield their camelCase and snake_case parts, so "ContextWindow" matches "context window".
+func tokenize(text string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) {
+		parts := splitIdentifier(word)
+		if len(parts) > 1 {
+			terms = appendTerm(terms, strings.ReplaceAll(word, "_", ""))
+		}
+		for _, part := range parts {
+			terms = appendTerm(terms, part)
+		}
+	}
+	return terms
+}
+
+func appendTerm(terms []string, term string) []string {
+	term = strings.ToLower(term)
+	if len(term) < 2 || stopwords[term] {
+		return terms
+	}
+	return append(terms, term)
+}
+
+// splitIdentifier splits a word on underscores and lower to upper case changes
+func splitIdentifier(word string) []string {
+	var parts []string
+	for _, piece := range strings.Split(word, "_") {
+		runes := []rune(piece)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			if unicode.IsUpper(runes[i]) && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])) {
+				parts = append(parts, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start < len(runes) {
+			parts = append(parts, string(runes[start:]))
+		}
+	}
+	return parts
+}
+
+// split breaks a document into passages of blank line separated blocks.
+// Blocks are joined until a passage reaches maxPassageWords, and a Markdown heading always starts a new passage.
+func split(path string, text string) []Passage {
+	markdown := strings.HasSuffix(strings.ToLower(path), ".md")
+
+	var passages []Passage
+	var block []string
+	start, last, words := 0, 0, 0
+
+	flush := func() {
+		if words > 0 {
+			content := strings.TrimSpace(strings.Join(block, "\n"))
+			passages = append(passages, Passage{Path: path, StartLine: start, EndLine: last, Text: content})
+		}
+		block = nil
+		words = 0
+	}
+
+	for i, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		n := i + 1
+		blank := strings.TrimSpace(line) == ""
+		heading := markdown && strings.HasPrefix(line, "#")
+
+		if len(block) > 0 && (heading || blank && words >= maxPassageWords) {
+			flush()
+		}
+		if blank && len(block) == 0 {
+			continue
+		}
+		if len(block) == 0 {
+			start = n
+		}
+
+		block = append(block, line)
+		if !blank {
+			last = n
+			words += len(strings.Fields(line))
+		}
+		if words >= 2*maxPassageWords {
+			flush()
+		}
+	}
+	flush()
+
+	return passages
+}
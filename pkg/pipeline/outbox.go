@@ -0,0 +1,127 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Pending is a transcript chunk waiting for the provider to come back
+type Pending struct {
+	Chunk  string    `json:"chunk"`
+	Queued time.Time `json:"queued"`
+}
+
+// Outbox keeps the chunks that could not be answered because the provider was unreachable,
+// in order and saved to disk so they survive a restart
+type Outbox struct {
+	path string
+
+	mu      sync.Mutex
+	pending []Pending
+}
+
+// NewOutbox loads the chunks still pending in path.
+// An empty path keeps them in memory only.
+func NewOutbox(path string) (*Outbox, error) {
+	o := &Outbox{path: path}
+	if path == "" {
+		return o, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return o, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read offline queue: %w", err)
+	}
+	if err := json.Unmarshal(data, &o.pending); err != nil {
+		return nil, fmt.Errorf("failed to decode offline queue %s: %w", path, err)
+	}
+	return o, nil
+}
+
+// Push adds a chunk at the end of the queue
+func (o *Outbox) Push(chunk string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.pending = append(o.pending, Pending{Chunk: chunk, Queued: time.Now()})
+	return o.save()
+}
+
+// Peek returns the oldest pending chunk
+func (o *Outbox) Peek() (Pending, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.pending) == 0 {
+		return Pending{}, false
+	}
+	return o.pending[0], true
+}
+
+// Pop removes the oldest pending chunk once it is answered.
+// Nothing is removed if the queue was cleared meanwhile and p is no longer the oldest.
+func (o *Outbox) Pop(p Pending) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.pending) == 0 || o.pending[0] != p {
+		return nil
+	}
+	o.pending = o.pending[1:]
+	return o.save()
+}
+
+// Len returns the number of pending chunks
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.pending)
+}
+
+// Clear drops all pending chunks
+func (o *Outbox) Clear() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.pending = nil
+	return o.save()
+}
+
+// save writes the queue through a temporary file, removing the file once the queue is empty
+func (o *Outbox) save() error {
+	if o.path == "" {
+		return nil
+	}
+
+	if len(o.pending) == 0 {
+		if err := os.Remove(o.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove offline queue: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(o.pending, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode offline queue: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(o.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create offline queue directory: %w", err)
+	}
+
+	tmp := o.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to save offline queue: %w", err)
+	}
+	if err := os.Rename(tmp, o.path); err != nil {
+		return fmt.Errorf("failed to save offline queue: %w", err)
+	}
+	return nil
+}
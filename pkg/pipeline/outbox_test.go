@@ -0,0 +1,52 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutboxPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+
+	outbox, err := NewOutbox(path)
+	if err != nil {
+		t.Fatalf("NewOutbox() error: %v", err)
+	}
+	outbox.Push("first?")
+	outbox.Push("second?")
+
+	reloaded, err := NewOutbox(path)
+	if err != nil {
+		t.Fatalf("NewOutbox() error: %v", err)
+	}
+	if reloaded.Len() != 2 {
+		t.Fatalf("Expected 2 pending chunks after reload, got: %d", reloaded.Len())
+	}
+
+	first, _ := reloaded.Peek()
+	if first.Chunk != "first?" {
+		t.Errorf("Expected the oldest chunk first, got: %q", first.Chunk)
+	}
+
+	reloaded.Pop(first)
+	reloaded.Pop(first)
+	if next, _ := reloaded.Peek(); reloaded.Len() != 1 || next.Chunk != "second?" {
+		t.Errorf("Expected only a matching chunk popped, got %d pending", reloaded.Len())
+	}
+
+	second, _ := reloaded.Peek()
+	reloaded.Pop(second)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the file removed once the queue is empty, got: %v", err)
+	}
+}
+
+func TestOutboxRejectsCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	os.WriteFile(path, []byte("{not json"), 0o644)
+
+	if _, err := NewOutbox(path); err == nil {
+		t.Error("Expected an error for a corrupt queue file")
+	}
+}
@@ -0,0 +1,725 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dimitarkovachev/eng-assist/pkg/ai"
+	"github.com/dimitarkovachev/eng-assist/pkg/knowledge"
+	"github.com/dimitarkovachev/eng-assist/pkg/prompt"
+	"github.com/dimitarkovachev/eng-assist/pkg/state"
+	"github.com/dimitarkovachev/eng-assist/pkg/trigger"
+)
+
+const pollInterval = 100 * time.Millisecond
+
+// defaultRetryInterval is how often queued chunks are retried while the provider is unreachable
+const defaultRetryInterval = 15 * time.Second
+
+// maxCarry bounds the skipped transcript, in bytes, carried into the next answered chunk
+const maxCarry = 8000
+
+// defaultPassages is the number of knowledge passages added to a request unless configured
+const defaultPassages = 3
+
+// queueSize is the number of chunks waiting for an answer under the queue policy
+const queueSize = 16
+
+// ErrNothingToEscalate is returned when escalation is asked for before any answer
+var ErrNothingToEscalate = errors.New("no answered transcript to escalate")
+
+// escalationKey marks the context of a request asking for the large model of a cascade
+type escalationKey struct{}
+
+// errQueued is returned for chunks queued behind chunks still waiting for the provider
+var errQueued = fmt.Errorf("%w: older requests are still queued", ai.ErrUnreachable)
+
+// lateKey holds the time a retried chunk was queued in the context of its request
+type lateKey struct{}
+
+// Policy decides what happens to a request still in flight when more transcript arrives
+type Policy string
+
+const (
+	// PolicyCancel cancels the in-flight request and sends its chunk again merged with the new text
+	PolicyCancel Policy = "cancel"
+
+	// PolicyQueue lets the in-flight request finish and answers new chunks after it in order
+	PolicyQueue Policy = "queue"
+
+	// PolicyParallel answers every chunk in its own request as soon as it is ready.
+	// Answers are written whole when they finish so concurrent streams do not interleave.
+	PolicyParallel Policy = "parallel"
+)
+
+const systemPrompt = "You are an engineering assistant listening to a live conversation. " +
+	"The user message is the latest part of the transcript. " +
+	"If it contains a question or a problem, answer it concisely. Otherwise reply briefly with useful context."
+
+// Pipeline connects the transcript to the AI tool.
+// It waits for the transcript to go quiet for the buffer timeout and then
+// sends everything written since the previous chunk to the tool.
+type Pipeline struct {
+	tool           ai.Tool
+	appState       *state.AppState
+	conversation   *ai.Conversation
+	trigger        trigger.Trigger
+	prompts        *prompt.Library
+	knowledge      *knowledge.Index
+	passages       int
+	policy         Policy
+	outbox         *Outbox
+	bufferTimeout  time.Duration
+	requestTimeout time.Duration
+	retryInterval  time.Duration
+	logger         *log.Logger
+
+	queue       chan string
+	escalations chan struct{}
+	wg          sync.WaitGroup
+
+	// output is held by an answer while it writes to the responses, so answers never interleave
+	output sync.Mutex
+
+	mu sync.Mutex
+
+	// carry is transcript the trigger skipped, kept as context for the next answer
+	carry string
+
+	// active holds the requests in flight so they can be interrupted
+	active map[*request]struct{}
+
+	// interrupted is the chunk of a cancelled request, merged into the next one
+	interrupted string
+
+	// last is the most recently answered chunk, asked again on escalation
+	last string
+
+	// draining is set while queued chunks are being retried
+	draining bool
+}
+
+// request is a transcript chunk being answered
+type request struct {
+	cancel      context.CancelFunc
+	done        chan struct{}
+	interrupted bool
+
+	// escalation asks an answered chunk again, so new transcript does not interrupt it
+	escalation bool
+}
+
+// Options holds the pipeline settings. Durations are in seconds.
+type Options struct {
+	// BufferTimeout is the silence after which buffered transcript is sent
+	BufferTimeout float64
+
+	// RequestTimeout bounds each AI request including retries, 0 means no limit
+	RequestTimeout float64
+
+	// ContextWindow is the model's context window in tokens, used to decide when to summarize history
+	ContextWindow int
+
+	// Trigger decides which chunks get an answer, nil uses the heuristic question detector
+	Trigger trigger.Trigger
+
+	// Prompts renders the system prompt of the active persona, nil uses the built-in prompt
+	Prompts *prompt.Library
+
+	// Policy decides what happens to a request in flight when more transcript arrives, empty means cancel
+	Policy Policy
+
+	// Knowledge grounds answers in the team's documents, nil disables retrieval
+	Knowledge *knowledge.Index
+
+	// Passages is the number of knowledge passages added to each request, 0 uses the default
+	Passages int
+
+	// Outbox keeps chunks while the provider is unreachable, nil keeps them in memory only
+	Outbox *Outbox
+
+	// RetryInterval is the time between retries of queued chunks, 0 uses the default
+	RetryInterval float64
+}
+
+// New creates a new pipeline
+func New(tool ai.Tool, appState *state.AppState, opts Options, logger *log.Logger) *Pipeline {
+	if opts.Trigger == nil {
+		opts.Trigger = trigger.NewHeuristic()
+	}
+	if opts.Policy == "" {
+		opts.Policy = PolicyCancel
+	}
+	if opts.Passages <= 0 {
+		opts.Passages = defaultPassages
+	}
+	if opts.Outbox == nil {
+		opts.Outbox, _ = NewOutbox("")
+	}
+	retryInterval := seconds(opts.RetryInterval)
+	if retryInterval <= 0 {
+		retryInterval = defaultRetryInterval
+	}
+
+	return &Pipeline{
+		tool:           tool,
+		appState:       appState,
+		conversation:   ai.NewConversation(opts.ContextWindow),
+		trigger:        opts.Trigger,
+		prompts:        opts.Prompts,
+		knowledge:      opts.Knowledge,
+		passages:       opts.Passages,
+		policy:         opts.Policy,
+		outbox:         opts.Outbox,
+		bufferTimeout:  seconds(opts.BufferTimeout),
+		requestTimeout: seconds(opts.RequestTimeout),
+		retryInterval:  retryInterval,
+		logger:         logger,
+		queue:          make(chan string, queueSize),
+		escalations:    make(chan struct{}, 1),
+		active:         make(map[*request]struct{}),
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Run watches the transcript until the context is cancelled.
+// Requests still in flight are cancelled with the context and waited for before it returns.
+func (p *Pipeline) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	retry := time.NewTicker(p.retryInterval)
+	defer retry.Stop()
+	defer p.wg.Wait()
+
+	p.appState.SetQueued(p.outbox.Len())
+
+	if p.policy == PolicyQueue {
+		p.wg.Add(1)
+		go p.work(ctx)
+	}
+
+	var lastChange time.Time
+	buffering := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-retry.C:
+			p.drain(ctx)
+		case <-p.escalations:
+			p.mu.Lock()
+			chunk := p.last
+			p.mu.Unlock()
+			p.start(context.WithValue(ctx, escalationKey{}, true), chunk, false)
+		case now := <-ticker.C:
+			if _, hasNew, _ := p.appState.TranscriptState.Read(); hasNew {
+				lastChange = now
+				buffering = true
+				if p.policy == PolicyCancel {
+					p.interrupt()
+				}
+				continue
+			}
+
+			if !buffering || now.Sub(lastChange) < p.bufferTimeout || p.appState.IsPaused() {
+				continue
+			}
+			buffering = false
+
+			chunk := strings.TrimSpace(p.appState.TranscriptState.Drain())
+			if chunk == "" {
+				continue
+			}
+
+			p.dispatch(ctx, chunk)
+		}
+	}
+}
+
+// dispatch hands a chunk to a request according to the policy
+func (p *Pipeline) dispatch(ctx context.Context, chunk string) {
+	switch p.policy {
+	case PolicyQueue:
+		select {
+		case p.queue <- chunk:
+		case <-ctx.Done():
+		}
+	case PolicyParallel:
+		p.start(ctx, chunk, false)
+	default:
+		// The request in flight was interrupted when this chunk started arriving,
+		// wait for it to stop so its text can be merged in
+		p.mu.Lock()
+		var pending []*request
+		for r := range p.active {
+			if r.interrupted {
+				pending = append(pending, r)
+			}
+		}
+		p.mu.Unlock()
+		for _, r := range pending {
+			<-r.done
+		}
+
+		p.mu.Lock()
+		if p.interrupted != "" {
+			chunk = p.interrupted + " " + chunk
+			p.interrupted = ""
+		}
+		p.mu.Unlock()
+
+		p.start(ctx, chunk, true)
+	}
+}
+
+// work answers queued chunks one at a time
+func (p *Pipeline) work(ctx context.Context) {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case chunk := <-p.queue:
+			p.run(ctx, chunk, true)
+		}
+	}
+}
+
+// start answers the chunk in the background
+func (p *Pipeline) start(ctx context.Context, chunk string, live bool) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.run(ctx, chunk, live)
+	}()
+}
+
+// run answers the chunk as a request that can be interrupted
+func (p *Pipeline) run(ctx context.Context, chunk string, live bool) {
+	reqCtx, cancel := context.WithCancel(ctx)
+	r := &request{cancel: cancel, done: make(chan struct{}), escalation: escalating(ctx)}
+
+	p.mu.Lock()
+	p.active[r] = struct{}{}
+	p.mu.Unlock()
+
+	sent, err := p.handle(reqCtx, chunk, live)
+	cancel()
+
+	p.mu.Lock()
+	delete(p.active, r)
+	interrupted := r.interrupted && err != nil
+	if interrupted {
+		p.interrupted = strings.TrimSpace(p.interrupted + " " + sent)
+	}
+	p.mu.Unlock()
+	close(r.done)
+
+	switch {
+	case interrupted:
+		p.logger.Printf("Interrupted the request for %q, more transcript arrived", sent)
+	case ctx.Err() != nil:
+		// Shutting down
+	case unreachable(err):
+		p.enqueue(sent)
+	case errors.Is(err, context.Canceled):
+		// Reset
+	case errors.Is(err, ai.ErrBudgetExhausted):
+		// Shown as the exhausted budget
+	default:
+		p.report(err)
+	}
+}
+
+// unreachable reports whether a request failed for lack of a complete response, so it is worth queueing.
+// Running out of the request timeout counts, a hung connection looks no different.
+func unreachable(err error) bool {
+	return errors.Is(err, ai.ErrUnreachable) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// interrupt cancels the requests in flight so their chunks are sent again with the new transcript.
+// Escalations keep running, their chunk was already answered and is not sent again.
+func (p *Pipeline) interrupt() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for r := range p.active {
+		if r.escalation {
+			continue
+		}
+		r.interrupted = true
+		r.cancel()
+	}
+}
+
+// Escalate asks the last answered chunk again of the large model of a cascade.
+// The answer is added to the responses like any other.
+func (p *Pipeline) Escalate() error {
+	p.mu.Lock()
+	last := p.last
+	p.mu.Unlock()
+	if last == "" {
+		return ErrNothingToEscalate
+	}
+
+	select {
+	case p.escalations <- struct{}{}:
+	default:
+		// An escalation is already waiting
+	}
+	return nil
+}
+
+// trimCarry keeps the last maxCarry bytes of text, starting at a word boundary
+// or at least at a rune boundary so no word or character is cut in half
+func trimCarry(text string) string {
+	if len(text) <= maxCarry {
+		return text
+	}
+
+	cut := len(text) - maxCarry
+	if i := strings.IndexByte(text[cut:], ' '); i >= 0 {
+		return text[cut+i+1:]
+	}
+	for cut < len(text) && !utf8.RuneStart(text[cut]) {
+		cut++
+	}
+	return text[cut:]
+}
+
+func escalating(ctx context.Context) bool {
+	escalate, _ := ctx.Value(escalationKey{}).(bool)
+	return escalate
+}
+
+// handle runs the chunk through the trigger and answers it when needed.
+// Skipped chunks are carried over so the next answer still sees them.
+// It returns the chunk including any carried text, so an interrupted request can be sent again.
+func (p *Pipeline) handle(ctx context.Context, chunk string, live bool) (string, error) {
+	if escalating(ctx) {
+		return chunk, p.answer(ctx, chunk, live)
+	}
+
+	decision, err := p.trigger.Decide(ctx, chunk)
+	if err != nil {
+		return chunk, err
+	}
+	if decision.Response != nil {
+		p.record(decision.Response)
+	}
+
+	p.logger.Printf("trigger: answer=%t reason=%q chunk=%d bytes", decision.Answer, decision.Reason, len(chunk))
+
+	p.mu.Lock()
+	if !decision.Answer {
+		p.carry = trimCarry(strings.TrimSpace(p.carry + " " + chunk))
+		p.mu.Unlock()
+		return chunk, nil
+	}
+
+	if p.carry != "" {
+		chunk = p.carry + " " + chunk
+		p.carry = ""
+	}
+	p.mu.Unlock()
+
+	// Answers keep their order, so nothing is asked while older chunks wait for the provider
+	if p.outbox.Len() > 0 {
+		return chunk, errQueued
+	}
+
+	return chunk, p.answer(ctx, chunk, live)
+}
+
+// enqueue keeps a chunk the provider could not be reached for until it is back
+func (p *Pipeline) enqueue(chunk string) {
+	if err := p.outbox.Push(chunk); err != nil {
+		p.logger.Printf("Error saving the offline queue: %v", err)
+	}
+
+	queued := p.outbox.Len()
+	p.appState.SetQueued(queued)
+	p.appState.SetAIError(fmt.Sprintf("AI provider unreachable, %d requests queued", queued))
+	p.logger.Printf("AI provider unreachable, queued %q", chunk)
+}
+
+// drain answers the queued chunks in order until the queue is empty, the provider is still unreachable
+// or the budget is exhausted.
+// Late answers are written once complete and marked with the time their chunk was queued.
+func (p *Pipeline) drain(ctx context.Context) {
+	p.mu.Lock()
+	if p.draining || p.outbox.Len() == 0 {
+		p.mu.Unlock()
+		return
+	}
+	p.draining = true
+	p.mu.Unlock()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer func() {
+			p.mu.Lock()
+			p.draining = false
+			p.mu.Unlock()
+		}()
+
+		for {
+			pending, ok := p.outbox.Peek()
+			if !ok {
+				return
+			}
+
+			err := p.answer(context.WithValue(ctx, lateKey{}, pending.Queued), pending.Chunk, false)
+			if unreachable(err) || errors.Is(err, ai.ErrBudgetExhausted) || ctx.Err() != nil {
+				return
+			}
+
+			// Other failures drop the chunk so the rest of the queue is not held up
+			if err := p.outbox.Pop(pending); err != nil {
+				p.logger.Printf("Error saving the offline queue: %v", err)
+			}
+			p.appState.SetQueued(p.outbox.Len())
+			p.report(err)
+		}
+	}()
+}
+
+// report surfaces the outcome of a request in the app state so the UI can show failures
+func (p *Pipeline) report(err error) {
+	if err == nil {
+		p.appState.SetAIError("")
+		return
+	}
+
+	p.logger.Printf("Error answering transcript chunk: %v", err)
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("AI request timed out after %s", p.requestTimeout)
+	}
+	p.appState.SetAIError(err.Error())
+}
+
+// answer writes the reply for a transcript chunk into the responses.
+// A live answer is streamed as it is generated, otherwise it is written once complete.
+func (p *Pipeline) answer(ctx context.Context, chunk string, live bool) error {
+	if p.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.requestTimeout)
+		defer cancel()
+	}
+
+	// An escalation asks the answered chunk again in place of its exchange in the history
+	req := p.conversation.Request(p.systemPrompt(), chunk)
+	if escalating(ctx) {
+		req = p.conversation.RequestAgain(p.systemPrompt(), chunk)
+	}
+	req.Cache = true
+	req.Escalate = escalating(ctx)
+	p.ground(&req, chunk)
+
+	events, err := p.tool.Stream(ctx, req)
+	if errors.Is(err, ai.ErrBudgetExhausted) {
+		if !p.appState.IsBudgetExhausted() {
+			p.logger.Printf("AI budget exhausted, skipping AI requests")
+		}
+		p.appState.SetBudgetExhausted(true)
+		return err
+	}
+	p.appState.SetBudgetExhausted(false)
+	if err != nil {
+		return err
+	}
+
+	p.appState.BeginResponse()
+	defer p.appState.EndResponse()
+
+	// A live answer holds the output while it streams, others are written whole before or after it
+	if live {
+		p.output.Lock()
+	}
+
+	var reply, calls strings.Builder
+	var final, spent *ai.Response
+	for event := range events {
+		if event.Err != nil {
+			err = event.Err
+			continue
+		}
+		if event.Partial != nil {
+			spent = event.Partial
+			continue
+		}
+		if event.Response != nil {
+			final = event.Response
+			p.record(final)
+			continue
+		}
+		if event.ToolCall != nil {
+			marker := fmt.Sprintf("[tool: %s %s]\n", event.ToolCall.Name, event.ToolCall.Input)
+			p.logger.Printf("AI called %s with %s", event.ToolCall.Name, event.ToolCall.Input)
+			if live {
+				p.appState.AiResponsesState.Write(marker)
+			}
+			calls.WriteString(marker)
+			continue
+		}
+		if event.Delta != "" {
+			if live {
+				p.appState.AiResponsesState.Write(event.Delta)
+			}
+			reply.WriteString(event.Delta)
+		}
+	}
+
+	// A cancelled stream may close without a final event
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	// A stream that ended early is still billed for its input and the text generated so far
+	if final == nil && spent != nil {
+		billed := *spent
+		billed.Usage.OutputTokens += ai.EstimateTokens(reply.String())
+		billed.Cost += ai.Cost(billed.Model, ai.Usage{OutputTokens: ai.EstimateTokens(reply.String())})
+		p.record(&billed)
+	}
+
+	if !live {
+		p.output.Lock()
+	}
+	if reply.Len() > 0 {
+		tail := "\n\n"
+		switch {
+		case errors.Is(err, context.Canceled):
+			tail = " [interrupted]" + tail
+		case unreachable(err):
+			tail = " [connection lost]" + tail
+		}
+		if final != nil && final.Route != "" {
+			tail = fmt.Sprintf("\n[%s, %s, $%.4f]", final.Model, final.Route, final.Cost) + tail
+		}
+		if !live {
+			tail = calls.String() + reply.String() + tail
+		}
+		if queued, ok := ctx.Value(lateKey{}).(time.Time); ok {
+			tail = fmt.Sprintf("[late, asked at %s] ", queued.Format("15:04")) + tail
+		}
+		p.appState.AiResponsesState.Write(tail)
+	}
+	p.output.Unlock()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.last = chunk
+	p.mu.Unlock()
+
+	p.remember(ctx, chunk, reply.String())
+	return nil
+}
+
+// ground adds the knowledge passages matching the chunk to the newest message.
+// They are kept out of the system prompt and the history so the cached prefix stays stable.
+func (p *Pipeline) ground(req *ai.Request, chunk string) {
+	if p.knowledge == nil {
+		return
+	}
+
+	results := p.knowledge.Search(chunk, p.passages)
+	if len(results) == 0 {
+		return
+	}
+
+	var content strings.Builder
+	content.WriteString("Passages from the team's documents that may be relevant. " +
+		"Cite the ones you use by their source in brackets, for example [path/to/doc.md:10-24].\n\n")
+	citations := make([]string, len(results))
+	for i, result := range results {
+		fmt.Fprintf(&content, "[%s]\n%s\n\n", result.Citation(), result.Text)
+		citations[i] = result.Citation()
+	}
+	fmt.Fprintf(&content, "%s%s", ai.TranscriptMarker, chunk)
+
+	req.Messages[len(req.Messages)-1].Content = content.String()
+	p.logger.Printf("knowledge: passages=%s", strings.Join(citations, ","))
+}
+
+// systemPrompt renders the active persona, falling back to the built-in prompt
+func (p *Pipeline) systemPrompt() string {
+	if p.prompts == nil {
+		return systemPrompt
+	}
+
+	transcript, _ := p.appState.TranscriptState.GetAll()
+	rendered, err := p.prompts.Render(transcript)
+	if err != nil {
+		p.logger.Printf("Error rendering persona, using the built-in prompt: %v", err)
+		return systemPrompt
+	}
+	return rendered
+}
+
+// remember adds the exchange to the conversation, an escalation replaces the one it asked again,
+// and summarizes old history when it grows too large
+func (p *Pipeline) remember(ctx context.Context, chunk string, reply string) {
+	if escalating(ctx) {
+		p.conversation.Replace(chunk, reply)
+	} else {
+		p.conversation.Add(chunk, reply)
+	}
+
+	resp, err := p.conversation.Compact(ctx, p.tool)
+	if err != nil {
+		p.logger.Printf("Error compacting conversation: %v", err)
+		return
+	}
+	if resp != nil {
+		p.record(resp)
+	}
+}
+
+// Reset cancels the requests in flight, drops queued chunks and forgets the conversation history,
+// used when the session is cleared
+func (p *Pipeline) Reset() {
+	p.conversation.Reset()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.carry = ""
+	p.interrupted = ""
+	p.last = ""
+	if err := p.outbox.Clear(); err != nil {
+		p.logger.Printf("Error clearing the offline queue: %v", err)
+	}
+	for r := range p.active {
+		r.interrupted = false
+		r.cancel()
+	}
+}
+
+// record adds the usage of a finished response to the cost ledger
+func (p *Pipeline) record(resp *ai.Response) {
+	p.appState.AddCost(state.CostEntry{
+		Model:            resp.Model,
+		InputTokens:      resp.Usage.InputTokens,
+		OutputTokens:     resp.Usage.OutputTokens,
+		CacheWriteTokens: resp.Usage.CacheWriteTokens,
+		CacheReadTokens:  resp.Usage.CacheReadTokens,
+		Cost:             resp.Cost,
+		Route:            resp.Route,
+		Timestamp:        time.Now(),
+	})
+}
@@ -0,0 +1,697 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dimitarkovachev/eng-assist/pkg/ai"
+	"github.com/dimitarkovachev/eng-assist/pkg/knowledge"
+	"github.com/dimitarkovachev/eng-assist/pkg/state"
+)
+
+type fakeTool struct {
+	mu       sync.Mutex
+	requests []ai.Request
+}
+
+func (f *fakeTool) Complete(ctx context.Context, req ai.Request) (*ai.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, req)
+	return &ai.Response{Text: "answer", Model: "fake"}, nil
+}
+
+func (f *fakeTool) Stream(ctx context.Context, req ai.Request) (<-chan ai.StreamEvent, error) {
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	f.mu.Unlock()
+
+	events := make(chan ai.StreamEvent, 3)
+	events <- ai.StreamEvent{Delta: "ans"}
+	events <- ai.StreamEvent{Delta: "wer"}
+	events <- ai.StreamEvent{Response: &ai.Response{
+		Text:  "answer",
+		Model: "fake",
+		Usage: ai.Usage{InputTokens: 10, OutputTokens: 2},
+		Cost:  0.5,
+	}}
+	close(events)
+	return events, nil
+}
+
+func (f *fakeTool) CurrentCost() float64 {
+	return 0
+}
+
+func (f *fakeTool) prompts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var prompts []string
+	for _, req := range f.requests {
+		prompts = append(prompts, req.Messages[len(req.Messages)-1].Content)
+	}
+	return prompts
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPipelineSendsChunkAfterTimeout(t *testing.T) {
+	tool := &fakeTool{}
+	appState := state.NewAppState()
+	p := New(tool, appState, Options{BufferTimeout: 0.2}, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	appState.TranscriptState.Write("how do we ")
+	appState.TranscriptState.Write("deploy this?")
+
+	waitFor(t, func() bool { return len(tool.prompts()) == 1 })
+
+	if prompt := tool.prompts()[0]; prompt != "how do we deploy this?" {
+		t.Errorf("Expected buffered chunk, got: %s", prompt)
+	}
+
+	waitFor(t, func() bool {
+		responses, _ := appState.AiResponsesState.GetAll()
+		return responses == "answer\n\n"
+	})
+
+	ledger := appState.Ledger()
+	if len(ledger) != 1 || ledger[0].Model != "fake" || ledger[0].InputTokens != 10 {
+		t.Errorf("Expected response to be recorded in the ledger, got: %+v", ledger)
+	}
+	if appState.GetCost() != 0.5 {
+		t.Errorf("Expected total cost 0.5, got: %f", appState.GetCost())
+	}
+
+	appState.TranscriptState.Write("what is next?")
+	waitFor(t, func() bool { return len(tool.prompts()) == 2 })
+
+	if prompt := tool.prompts()[1]; prompt != "what is next?" {
+		t.Errorf("Expected only new text in second chunk, got: %s", prompt)
+	}
+
+	tool.mu.Lock()
+	history := tool.requests[1].Messages
+	tool.mu.Unlock()
+	if len(history) != 3 || history[0].Content != "how do we deploy this?" || history[1].Content != "answer" {
+		t.Errorf("Expected the previous exchange as history, got: %+v", history)
+	}
+}
+
+func TestPipelineHoldsChunkWhilePaused(t *testing.T) {
+	tool := &fakeTool{}
+	appState := state.NewAppState()
+	appState.SetPaused(true)
+	p := New(tool, appState, Options{BufferTimeout: 0.05}, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	appState.TranscriptState.Write("is it paused?")
+	time.Sleep(300 * time.Millisecond)
+
+	if len(tool.prompts()) != 0 {
+		t.Fatal("Expected no requests while paused")
+	}
+
+	appState.SetPaused(false)
+	waitFor(t, func() bool { return len(tool.prompts()) == 1 })
+}
+
+func TestPipelineStopsAtBudget(t *testing.T) {
+	tool := &fakeTool{}
+	appState := state.NewAppState()
+	appState.AddCost(state.CostEntry{Cost: 1, Timestamp: time.Now()})
+	budgeted := ai.NewBudgetedTool(tool, appState, ai.Budget{Session: 1})
+	p := New(budgeted, appState, Options{BufferTimeout: 0.05}, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	appState.TranscriptState.Write("expensive question?")
+	waitFor(t, appState.IsBudgetExhausted)
+
+	if len(tool.prompts()) != 0 {
+		t.Error("Expected no requests once the budget is exhausted")
+	}
+}
+
+type failingTool struct {
+	fakeTool
+	err error
+}
+
+func (f *failingTool) Stream(ctx context.Context, req ai.Request) (<-chan ai.StreamEvent, error) {
+	return nil, f.err
+}
+
+func TestPipelineReportsErrors(t *testing.T) {
+	tool := &failingTool{err: &ai.RetryError{Attempts: 5, Err: &ai.APIError{Provider: "test", StatusCode: 529, Message: "Overloaded"}}}
+	appState := state.NewAppState()
+	p := New(tool, appState, Options{BufferTimeout: 0.05}, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	appState.TranscriptState.Write("question?")
+	waitFor(t, func() bool { return appState.GetAIError() != "" })
+
+	if got := appState.GetAIError(); got != tool.err.Error() {
+		t.Errorf("Expected retry error in state, got: %s", got)
+	}
+}
+
+func TestPipelineCarriesSkippedChunks(t *testing.T) {
+	tool := &fakeTool{}
+	appState := state.NewAppState()
+	p := New(tool, appState, Options{BufferTimeout: 0.05}, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	appState.TranscriptState.Write("the cache hit rate dropped to ten percent")
+	time.Sleep(300 * time.Millisecond)
+
+	if len(tool.prompts()) != 0 {
+		t.Fatal("Expected statement without a question to be skipped")
+	}
+
+	appState.TranscriptState.Write("why would that happen?")
+	waitFor(t, func() bool { return len(tool.prompts()) == 1 })
+
+	if prompt := tool.prompts()[0]; prompt != "the cache hit rate dropped to ten percent why would that happen?" {
+		t.Errorf("Expected skipped chunk to be carried into the prompt, got: %s", prompt)
+	}
+}
+
+func TestTrimCarry(t *testing.T) {
+	words := strings.Repeat("ünïcödé ", maxCarry/8)
+	trimmed := trimCarry(words)
+	if len(trimmed) > maxCarry {
+		t.Errorf("Expected at most %d bytes, got %d", maxCarry, len(trimmed))
+	}
+	if !strings.HasPrefix(trimmed, "ünïcödé ") {
+		t.Errorf("Expected trimming at a word boundary, got prefix: %q", trimmed[:16])
+	}
+
+	word := strings.Repeat("ü", maxCarry)
+	trimmed = trimCarry(word)
+	if !utf8.ValidString(trimmed) || len(trimmed) > maxCarry {
+		t.Errorf("Expected a valid string of at most %d bytes, got %d bytes", maxCarry, len(trimmed))
+	}
+
+	if got := trimCarry("short"); got != "short" {
+		t.Errorf("Expected short text to be kept, got: %q", got)
+	}
+}
+
+// slowTool streams one delta and then holds the request until it is released or cancelled
+type slowTool struct {
+	fakeTool
+	release  chan struct{}
+	inFlight int
+	maxLive  int
+
+	// billed is sent as the usage billed before the delta when set
+	billed *ai.Response
+}
+
+func newSlowTool() *slowTool {
+	return &slowTool{release: make(chan struct{})}
+}
+
+func (f *slowTool) Stream(ctx context.Context, req ai.Request) (<-chan ai.StreamEvent, error) {
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	f.inFlight++
+	f.maxLive = max(f.maxLive, f.inFlight)
+	f.mu.Unlock()
+
+	events := make(chan ai.StreamEvent, 3)
+	if f.billed != nil {
+		events <- ai.StreamEvent{Partial: f.billed}
+	}
+	events <- ai.StreamEvent{Delta: "partial"}
+	go func() {
+		defer close(events)
+		defer func() {
+			f.mu.Lock()
+			f.inFlight--
+			f.mu.Unlock()
+		}()
+
+		select {
+		case <-f.release:
+			resp := &ai.Response{Text: "partial", Model: "fake"}
+			if req.Escalate {
+				resp.Route = ai.RouteRequested
+			}
+			events <- ai.StreamEvent{Response: resp}
+		case <-ctx.Done():
+			events <- ai.StreamEvent{Err: ctx.Err()}
+		}
+	}()
+	return events, nil
+}
+
+// finish releases one request in flight
+func (f *slowTool) finish(t *testing.T) {
+	t.Helper()
+	select {
+	case f.release <- struct{}{}:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for a request to release")
+	}
+}
+
+func (f *slowTool) peak() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.maxLive
+}
+
+func TestPipelineCancelsInFlightRequest(t *testing.T) {
+	tool := newSlowTool()
+	appState := state.NewAppState()
+	p := New(tool, appState, Options{BufferTimeout: 0.05}, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	appState.TranscriptState.Write("how do we roll back?")
+	waitFor(t, func() bool { return len(tool.prompts()) == 1 })
+
+	appState.TranscriptState.Write("the database one?")
+	waitFor(t, func() bool { return len(tool.prompts()) == 2 })
+
+	if prompt := tool.prompts()[1]; prompt != "how do we roll back? the database one?" {
+		t.Errorf("Expected the interrupted chunk merged into the new one, got: %s", prompt)
+	}
+
+	responses, _ := appState.AiResponsesState.GetAll()
+	if !strings.Contains(responses, "partial [interrupted]") {
+		t.Errorf("Expected the cancelled answer to be marked, got: %q", responses)
+	}
+	if appState.GetAIError() != "" {
+		t.Errorf("Expected no error for an interrupted request, got: %s", appState.GetAIError())
+	}
+}
+
+func TestPipelineRecordsCancelledUsage(t *testing.T) {
+	tool := newSlowTool()
+	tool.billed = &ai.Response{Model: "fake", Usage: ai.Usage{InputTokens: 100}, Cost: 0.3}
+	appState := state.NewAppState()
+	p := New(tool, appState, Options{BufferTimeout: 0.05}, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	appState.TranscriptState.Write("how do we roll back?")
+	waitFor(t, func() bool { return len(tool.prompts()) == 1 })
+
+	appState.TranscriptState.Write("the database one?")
+	waitFor(t, func() bool { return len(appState.Ledger()) == 1 })
+
+	entry := appState.Ledger()[0]
+	if entry.InputTokens != 100 || entry.OutputTokens != ai.EstimateTokens("partial") || entry.Cost != 0.3 {
+		t.Errorf("Expected the interrupted request's usage in the ledger, got: %+v", entry)
+	}
+}
+
+func TestPipelineDoesNotInterruptEscalation(t *testing.T) {
+	tool := newSlowTool()
+	appState := state.NewAppState()
+	p := New(tool, appState, Options{BufferTimeout: 0.05}, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	appState.TranscriptState.Write("how do we roll back?")
+	waitFor(t, func() bool { return len(tool.prompts()) == 1 })
+	tool.finish(t)
+	waitFor(t, func() bool { return p.Escalate() == nil })
+	waitFor(t, func() bool { return len(tool.prompts()) == 2 })
+
+	appState.TranscriptState.Write("the database one?")
+	waitFor(t, func() bool { return len(tool.prompts()) == 3 })
+
+	if prompt := tool.prompts()[2]; prompt != "the database one?" {
+		t.Errorf("Expected the answered chunk not to be merged again, got: %s", prompt)
+	}
+
+	// The escalation is written whole once the live answer is done
+	tool.finish(t)
+	tool.finish(t)
+	escalated := "partial\n[fake, escalated: requested, $0.0000]\n\n"
+	waitFor(t, func() bool {
+		responses, _ := appState.AiResponsesState.GetAll()
+		return strings.Contains(responses, escalated)
+	})
+
+	responses, _ := appState.AiResponsesState.GetAll()
+	if want := "partial\n\npartial\n\n" + escalated; responses != want {
+		t.Errorf("Expected the answers one after another, got: %q", responses)
+	}
+}
+
+func TestPipelineQueuesChunks(t *testing.T) {
+	tool := newSlowTool()
+	appState := state.NewAppState()
+	p := New(tool, appState, Options{BufferTimeout: 0.05, Policy: PolicyQueue}, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	appState.TranscriptState.Write("first question?")
+	waitFor(t, func() bool { return len(tool.prompts()) == 1 })
+
+	appState.TranscriptState.Write("second question?")
+	time.Sleep(300 * time.Millisecond)
+	if len(tool.prompts()) != 1 {
+		t.Fatal("Expected the second chunk to wait for the first answer")
+	}
+
+	tool.release <- struct{}{}
+	waitFor(t, func() bool { return len(tool.prompts()) == 2 })
+
+	if prompt := tool.prompts()[1]; prompt != "second question?" {
+		t.Errorf("Expected the queued chunk on its own, got: %s", prompt)
+	}
+}
+
+func TestPipelineRunsChunksInParallel(t *testing.T) {
+	tool := newSlowTool()
+	appState := state.NewAppState()
+	p := New(tool, appState, Options{BufferTimeout: 0.05, Policy: PolicyParallel}, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	appState.TranscriptState.Write("first question?")
+	waitFor(t, func() bool { return len(tool.prompts()) == 1 })
+
+	appState.TranscriptState.Write("second question?")
+	waitFor(t, func() bool { return len(tool.prompts()) == 2 })
+
+	if tool.peak() != 2 {
+		t.Errorf("Expected both requests in flight at once, got: %d", tool.peak())
+	}
+
+	close(tool.release)
+	waitFor(t, func() bool {
+		responses, _ := appState.AiResponsesState.GetAll()
+		return strings.Count(responses, "partial") == 2
+	})
+
+	responses, _ := appState.AiResponsesState.GetAll()
+	if responses != "partial\n\npartial\n\n" {
+		t.Errorf("Expected whole answers written in turn, got: %q", responses)
+	}
+}
+
+func TestPipelineReplaysCassette(t *testing.T) {
+	cassette, err := ai.NewCassette("testdata/deploy.json")
+	if err != nil {
+		t.Fatalf("NewCassette() error: %v", err)
+	}
+	appState := state.NewAppState()
+	p := New(cassette, appState, Options{BufferTimeout: 0.05}, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	appState.TranscriptState.Write("how do we   deploy this?")
+	waitFor(t, func() bool { return len(appState.Ledger()) == 1 })
+
+	responses, _ := appState.AiResponsesState.GetAll()
+	if responses != "Build the image, push it to the registry and roll the deployment with kubectl.\n\n" {
+		t.Errorf("Unexpected replayed answer: %q", responses)
+	}
+	if entry := appState.Ledger()[0]; entry.Model != "claude-sonnet-4-5" || entry.InputTokens != 62 || entry.Cost != 0 {
+		t.Errorf("Expected the recorded usage at no cost in the ledger, got: %+v", entry)
+	}
+}
+
+func TestPipelineGroundsAnswersInKnowledge(t *testing.T) {
+	index, err := knowledge.Build("../knowledge/testdata/docs")
+	if err != nil {
+		t.Fatalf("Build() error: %v", err)
+	}
+	tool := &fakeTool{}
+	appState := state.NewAppState()
+	p := New(tool, appState, Options{BufferTimeout: 0.05, Knowledge: index, Passages: 1}, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	appState.TranscriptState.Write("how do we roll back the deploy?")
+	waitFor(t, func() bool { return len(tool.prompts()) == 1 })
+
+	prompt := tool.prompts()[0]
+	if !strings.Contains(prompt, "[runbooks/deploy.md:6-10]\n## Rolling back") || strings.Contains(prompt, "cache.md") {
+		t.Errorf("Expected the top passage with its citation, got: %s", prompt)
+	}
+	if !strings.HasSuffix(prompt, "Transcript:\nhow do we roll back the deploy?") {
+		t.Errorf("Expected the chunk after the passages, got: %s", prompt)
+	}
+
+	appState.TranscriptState.Write("what is next?")
+	waitFor(t, func() bool { return len(tool.prompts()) == 2 })
+
+	tool.mu.Lock()
+	history := tool.requests[1].Messages
+	tool.mu.Unlock()
+	if history[0].Content != "how do we roll back the deploy?" {
+		t.Errorf("Expected the history without passages, got: %s", history[0].Content)
+	}
+}
+
+// callingTool announces a tool call before answering, like ai.ToolLoop does
+type callingTool struct {
+	fakeTool
+}
+
+func (f *callingTool) Stream(ctx context.Context, req ai.Request) (<-chan ai.StreamEvent, error) {
+	events := make(chan ai.StreamEvent, 3)
+	events <- ai.StreamEvent{ToolCall: &ai.ToolCall{ID: "call_1", Name: "calculate", Input: []byte(`{"expression":"6*7"}`)}}
+	events <- ai.StreamEvent{Delta: "42"}
+	events <- ai.StreamEvent{Response: &ai.Response{Text: "42", Model: "fake"}}
+	close(events)
+	return events, nil
+}
+
+func TestPipelineShowsToolCalls(t *testing.T) {
+	appState := state.NewAppState()
+	p := New(&callingTool{}, appState, Options{BufferTimeout: 0.1}, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	appState.TranscriptState.Write("what is six times seven?")
+
+	waitFor(t, func() bool {
+		responses, _ := appState.AiResponsesState.GetAll()
+		return responses == "[tool: calculate {\"expression\":\"6*7\"}]\n42\n\n"
+	})
+}
+
+// routingTool answers like a cascade, with the large model when escalation is asked for
+type routingTool struct {
+	fakeTool
+}
+
+func (f *routingTool) Stream(ctx context.Context, req ai.Request) (<-chan ai.StreamEvent, error) {
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	f.mu.Unlock()
+
+	resp := &ai.Response{Text: "quick", Model: "small", Route: ai.RouteSmall, Cost: 0.0001}
+	if req.Escalate {
+		resp = &ai.Response{Text: "thorough", Model: "large", Route: ai.RouteRequested, Cost: 0.02}
+	}
+
+	events := make(chan ai.StreamEvent, 2)
+	events <- ai.StreamEvent{Delta: resp.Text}
+	events <- ai.StreamEvent{Response: resp}
+	close(events)
+	return events, nil
+}
+
+func TestPipelineEscalatesLastChunk(t *testing.T) {
+	tool := &routingTool{}
+	appState := state.NewAppState()
+	p := New(tool, appState, Options{BufferTimeout: 0.1}, log.New(io.Discard, "", 0))
+
+	if err := p.Escalate(); err != ErrNothingToEscalate {
+		t.Errorf("Expected ErrNothingToEscalate before any answer, got: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	appState.TranscriptState.Write("how do we shard the queue?")
+	waitFor(t, func() bool {
+		responses, _ := appState.AiResponsesState.GetAll()
+		return responses == "quick\n[small, small, $0.0001]\n\n"
+	})
+
+	if err := p.Escalate(); err != nil {
+		t.Fatalf("Escalate() error: %v", err)
+	}
+	waitFor(t, func() bool {
+		responses, _ := appState.AiResponsesState.GetAll()
+		return strings.HasSuffix(responses, "thorough\n[large, escalated: requested, $0.0200]\n\n")
+	})
+
+	prompts := tool.prompts()
+	if len(prompts) != 2 || prompts[1] != "how do we shard the queue?" {
+		t.Errorf("Expected the last chunk asked again, got: %v", prompts)
+	}
+	if messages := tool.requests[1].Messages; len(messages) != 1 {
+		t.Errorf("Expected the escalation without the exchange it asks again, got: %+v", messages)
+	}
+
+	// The quick answer is replaced in the history
+	waitFor(t, func() bool {
+		history := p.conversation.Request("", "next").Messages
+		return len(history) == 3 && history[1].Content == "thorough"
+	})
+	if ledger := appState.Ledger(); len(ledger) != 2 || ledger[1].Route != ai.RouteRequested {
+		t.Errorf("Expected the route in the ledger, got: %+v", ledger)
+	}
+}
+
+// offlineTool fails as unreachable until it is brought online
+type offlineTool struct {
+	fakeTool
+	online atomic.Bool
+}
+
+func (f *offlineTool) Stream(ctx context.Context, req ai.Request) (<-chan ai.StreamEvent, error) {
+	if !f.online.Load() {
+		return nil, fmt.Errorf("failed to send request: %w", ai.ErrUnreachable)
+	}
+	return f.fakeTool.Stream(ctx, req)
+}
+
+func TestPipelineQueuesWhileOffline(t *testing.T) {
+	tool := &offlineTool{}
+	appState := state.NewAppState()
+	outbox, _ := NewOutbox(filepath.Join(t.TempDir(), "queue.json"))
+	p := New(tool, appState, Options{BufferTimeout: 0.1, RetryInterval: 0.2, Outbox: outbox}, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	appState.TranscriptState.Write("how do we deploy?")
+	waitFor(t, func() bool { return appState.Queued() == 1 })
+	appState.TranscriptState.Write("and roll back?")
+	waitFor(t, func() bool { return appState.Queued() == 2 })
+
+	if appState.GetAIError() == "" {
+		t.Error("Expected the unreachable provider to be reported")
+	}
+
+	tool.online.Store(true)
+	waitFor(t, func() bool { return appState.Queued() == 0 })
+
+	responses, _ := appState.AiResponsesState.GetAll()
+	late := regexp.MustCompile(`^(\[late, asked at \d{2}:\d{2}\] answer\n\n){2}$`)
+	if !late.MatchString(responses) {
+		t.Errorf("Expected two late answers, got: %q", responses)
+	}
+	if prompts := tool.prompts(); len(prompts) != 2 || prompts[0] != "how do we deploy?" || prompts[1] != "and roll back?" {
+		t.Errorf("Expected the queued chunks answered in order, got: %v", prompts)
+	}
+	if appState.GetAIError() != "" {
+		t.Errorf("Expected the error cleared once answered, got: %q", appState.GetAIError())
+	}
+}
+
+func TestPipelineKeepsQueueWhenBudgetExhausted(t *testing.T) {
+	tool := &fakeTool{}
+	appState := state.NewAppState()
+	appState.AddCost(state.CostEntry{Cost: 1, Timestamp: time.Now()})
+	outbox, _ := NewOutbox(filepath.Join(t.TempDir(), "queue.json"))
+	outbox.Push("how do we deploy?")
+	budgeted := ai.NewBudgetedTool(tool, appState, ai.Budget{Session: 1})
+	p := New(budgeted, appState, Options{BufferTimeout: 0.05, RetryInterval: 0.05, Outbox: outbox}, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	waitFor(t, appState.IsBudgetExhausted)
+	time.Sleep(200 * time.Millisecond)
+
+	if outbox.Len() != 1 || appState.Queued() != 1 {
+		t.Errorf("Expected the chunk kept queued, got %d queued", outbox.Len())
+	}
+	if len(tool.prompts()) != 0 {
+		t.Error("Expected no requests once the budget is exhausted")
+	}
+}
+
+// hangingTool never answers, like a provider behind a network that drops packets
+type hangingTool struct {
+	fakeTool
+}
+
+func (f *hangingTool) Stream(ctx context.Context, req ai.Request) (<-chan ai.StreamEvent, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestPipelineQueuesOnTimeout(t *testing.T) {
+	appState := state.NewAppState()
+	outbox, _ := NewOutbox("")
+	p := New(&hangingTool{}, appState, Options{BufferTimeout: 0.05, RequestTimeout: 0.05, RetryInterval: 60, Outbox: outbox}, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx)
+
+	appState.TranscriptState.Write("is the database up?")
+	waitFor(t, func() bool { return appState.Queued() == 1 })
+
+	if pending, _ := outbox.Peek(); pending.Chunk != "is the database up?" {
+		t.Errorf("Expected the timed out chunk queued, got: %q", pending.Chunk)
+	}
+}
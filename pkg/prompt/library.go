@@ -0,0 +1,137 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Extension is the file extension of persona templates
+const Extension = ".tmpl"
+
+// DefaultPersona is the persona used when none is named
+const DefaultPersona = "default"
+
+// Vars are the values available to persona templates
+type Vars struct {
+	Transcript string
+	Date       string
+	Notes      string
+}
+
+// Library holds the named system prompt templates and the active persona
+type Library struct {
+	mu       sync.RWMutex
+	personas map[string]*template.Template
+	active   string
+	notes    string
+}
+
+// Load parses every template in dir. The persona name is the file name without the extension.
+// An empty active name selects DefaultPersona.
+func Load(dir string, active string) (*Library, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+Extension))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list personas: %w", err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no %s persona templates found in %s", Extension, dir)
+	}
+
+	personas := make(map[string]*template.Template, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read persona: %w", err)
+		}
+
+		name := strings.TrimSuffix(filepath.Base(path), Extension)
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse persona %s: %w", name, err)
+		}
+		personas[name] = tmpl
+	}
+
+	library := &Library{personas: personas}
+	if active == "" {
+		if _, ok := personas[DefaultPersona]; !ok {
+			return nil, fmt.Errorf("no %s persona in %s, name one with AI_PERSONA", DefaultPersona, dir)
+		}
+		active = DefaultPersona
+	}
+	if err := library.SetActive(active); err != nil {
+		return nil, err
+	}
+
+	return library, nil
+}
+
+// Names returns the persona names in alphabetical order
+func (l *Library) Names() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	names := make([]string, 0, len(l.personas))
+	for name := range l.personas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Active returns the name of the active persona
+func (l *Library) Active() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.active
+}
+
+// SetActive switches the persona used by Render
+func (l *Library) SetActive(name string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.personas[name]; !ok {
+		return fmt.Errorf("unknown persona %q", name)
+	}
+	l.active = name
+	return nil
+}
+
+// Notes returns the user notes passed to templates
+func (l *Library) Notes() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.notes
+}
+
+// SetNotes replaces the user notes passed to templates
+func (l *Library) SetNotes(notes string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.notes = notes
+}
+
+// Render executes the active persona with the recent transcript, today's date and the user notes
+func (l *Library) Render(transcript string) (string, error) {
+	l.mu.RLock()
+	tmpl := l.personas[l.active]
+	vars := Vars{
+		Transcript: transcript,
+		Date:       time.Now().Format("Monday, 2 January 2006"),
+		Notes:      l.notes,
+	}
+	l.mu.RUnlock()
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, vars); err != nil {
+		return "", fmt.Errorf("failed to render persona %s: %w", tmpl.Name(), err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
@@ -0,0 +1,125 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePersonas(t *testing.T, personas map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, body := range personas {
+		if err := os.WriteFile(filepath.Join(dir, name+Extension), []byte(body), 0o644); err != nil {
+			t.Fatalf("WriteFile() error: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestLoadAndRender(t *testing.T) {
+	dir := writePersonas(t, map[string]string{
+		"scribe": "Scribe on {{.Date}}. {{.Transcript}}",
+		"helper": "Helper. Notes: {{.Notes}}",
+	})
+
+	library, err := Load(dir, "helper")
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	names := library.Names()
+	if len(names) != 2 || names[0] != "helper" || names[1] != "scribe" {
+		t.Errorf("Expected sorted names, got: %v", names)
+	}
+	if library.Active() != "helper" {
+		t.Errorf("Expected the named persona to be active, got: %s", library.Active())
+	}
+
+	library.SetNotes("be brief")
+	out, err := library.Render("ignored")
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	if out != "Helper. Notes: be brief" {
+		t.Errorf("Unexpected render: %s", out)
+	}
+
+	if err := library.SetActive("scribe"); err != nil {
+		t.Fatalf("SetActive() error: %v", err)
+	}
+	out, err = library.Render("hello there")
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	if !strings.HasPrefix(out, "Scribe on ") || !strings.HasSuffix(out, "hello there") {
+		t.Errorf("Unexpected render: %s", out)
+	}
+}
+
+func TestLoadSelectsDefault(t *testing.T) {
+	dir := writePersonas(t, map[string]string{"default": "Default", "assistant": "Assistant"})
+
+	library, err := Load(dir, "")
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if library.Active() != DefaultPersona {
+		t.Errorf("Expected the default persona to be active, got: %s", library.Active())
+	}
+
+	dir = writePersonas(t, map[string]string{"assistant": "Assistant"})
+	if _, err := Load(dir, ""); err == nil {
+		t.Error("Expected an error without a default persona")
+	}
+}
+
+func TestSetActiveUnknown(t *testing.T) {
+	dir := writePersonas(t, map[string]string{"helper": "Helper"})
+
+	library, err := Load(dir, "helper")
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if err := library.SetActive("missing"); err == nil {
+		t.Error("Expected error for unknown persona")
+	}
+	if library.Active() != "helper" {
+		t.Errorf("Expected active persona to be unchanged, got: %s", library.Active())
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, err := Load(t.TempDir(), ""); err == nil {
+		t.Error("Expected error for empty directory")
+	}
+
+	dir := writePersonas(t, map[string]string{"broken": "{{.Transcript"})
+	if _, err := Load(dir, ""); err == nil {
+		t.Error("Expected error for invalid template")
+	}
+
+	dir = writePersonas(t, map[string]string{"helper": "Helper"})
+	if _, err := Load(dir, "missing"); err == nil {
+		t.Error("Expected error for unknown active persona")
+	}
+}
+
+func TestShippedPersonas(t *testing.T) {
+	library, err := Load("../../prompts", "")
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if library.Active() != DefaultPersona {
+		t.Errorf("Expected the default persona to be active, got: %s", library.Active())
+	}
+
+	for _, name := range library.Names() {
+		library.SetActive(name)
+		library.SetNotes("some notes")
+		if _, err := library.Render("some transcript"); err != nil {
+			t.Errorf("Render(%s) error: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package redact
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Rule masks the text matching a pattern with numbered placeholders of its label
+type Rule struct {
+	Label   string
+	Pattern *regexp.Regexp
+
+	// Valid optionally rejects matches, like numbers failing a checksum
+	Valid func(match string) bool
+}
+
+// DefaultRules mask secrets and personal data commonly read aloud in meetings.
+// Tokens come first so their digits are not taken for phone or card numbers.
+var DefaultRules = []Rule{
+	{Label: "AWS_KEY", Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Label: "GITHUB_TOKEN", Pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{Label: "EMAIL", Pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+	{Label: "CARD", Pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), Valid: luhn},
+	{Label: "PHONE", Pattern: regexp.MustCompile(`\+\d{1,3}[ .-]?\d{2,4}[ .-]\d{3,4}[ .-]\d{3,4}\b|(?:\(\d{3}\)[ .-]?|\b\d{3}[ .-])\d{3}[ .-]\d{4}\b`)},
+}
+
+// placeholder matches the masks written by a redactor
+var placeholder = regexp.MustCompile(`\[[A-Z_]+_\d+\]`)
+
+// maxPlaceholder bounds the length of a mask, used to hold back partial masks while streaming
+const maxPlaceholder = 32
+
+// Redactor replaces sensitive text with placeholders like [EMAIL_1] and restores them locally.
+// The same value always gets the same placeholder, so masked prompts stay stable across requests.
+type Redactor struct {
+	rules []Rule
+
+	mu       sync.Mutex
+	masks    map[string]string // original to placeholder
+	values   map[string]string // placeholder to original
+	counters map[string]int
+}
+
+// New creates a redactor applying the rules in order
+func New(rules []Rule) *Redactor {
+	return &Redactor{
+		rules:    rules,
+		masks:    make(map[string]string),
+		values:   make(map[string]string),
+		counters: make(map[string]int),
+	}
+}
+
+// LoadRules reads user-defined patterns from a file, one regular expression per line.
+// Blank lines and lines starting with # are skipped.
+func LoadRules(path string) ([]Rule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open redaction patterns: %w", err)
+	}
+	defer file.Close()
+
+	var rules []Rule
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pattern, err := regexp.Compile(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid pattern: %w", path, n, err)
+		}
+		rules = append(rules, Rule{Label: "REDACTED", Pattern: pattern})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read redaction patterns: %w", err)
+	}
+	return rules, nil
+}
+
+// Redact returns the text with every match of the rules replaced by its placeholder
+func (r *Redactor) Redact(text string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rule := range r.rules {
+		text = rule.Pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if placeholder.MatchString(match) || rule.Valid != nil && !rule.Valid(match) {
+				return match
+			}
+			return r.mask(rule.Label, match)
+		})
+	}
+	return text
+}
+
+func (r *Redactor) mask(label string, value string) string {
+	if mask, ok := r.masks[value]; ok {
+		return mask
+	}
+
+	r.counters[label]++
+	mask := fmt.Sprintf("[%s_%d]", label, r.counters[label])
+	r.masks[value] = mask
+	r.values[mask] = value
+	return mask
+}
+
+// Restore returns the text with the placeholders of this redactor replaced by the original values
+func (r *Redactor) Restore(text string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return placeholder.ReplaceAllStringFunc(text, func(mask string) string {
+		if value, ok := r.values[mask]; ok {
+			return value
+		}
+		return mask
+	})
+}
+
+// Count returns the number of distinct values redacted so far
+func (r *Redactor) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.masks)
+}
+
+// luhn reports whether the digits of a card number pass the Luhn checksum
+func luhn(number string) bool {
+	sum, double := 0, false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		digit := int(c - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,199 @@
+package redact
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/dimitarkovachev/eng-assist/pkg/ai"
+)
+
+// Tool masks requests before they reach the wrapped tool and restores its replies,
+// so sensitive text never leaves the machine while callers keep working with the originals
+type Tool struct {
+	tool     ai.Tool
+	redactor *Redactor
+}
+
+// NewTool wraps the tool with the redactor
+func NewTool(tool ai.Tool, redactor *Redactor) *Tool {
+	return &Tool{
+		tool:     tool,
+		redactor: redactor,
+	}
+}
+
+// Complete sends the redacted request and returns the restored reply
+func (t *Tool) Complete(ctx context.Context, req ai.Request) (*ai.Response, error) {
+	resp, err := t.tool.Complete(ctx, t.redact(req))
+	if err != nil {
+		return nil, err
+	}
+	return t.restore(resp), nil
+}
+
+// Stream sends the redacted request and emits restored deltas.
+// A delta ending in what may be the start of a placeholder is held back until the placeholder is complete.
+func (t *Tool) Stream(ctx context.Context, req ai.Request) (<-chan ai.StreamEvent, error) {
+	upstream, err := t.tool.Stream(ctx, t.redact(req))
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan ai.StreamEvent)
+	go func() {
+		defer close(events)
+
+		var pending string
+		for event := range upstream {
+			switch {
+			case event.Delta != "":
+				var ready string
+				ready, pending = split(pending + event.Delta)
+				if ready == "" {
+					continue
+				}
+				event.Delta = t.redactor.Restore(ready)
+			default:
+				if pending != "" {
+					if !send(ctx, events, ai.StreamEvent{Delta: t.redactor.Restore(pending)}) {
+						return
+					}
+					pending = ""
+				}
+				if event.Response != nil {
+					event.Response = t.restore(event.Response)
+				}
+			}
+			if event.ToolCall != nil {
+				call := t.restoreCall(*event.ToolCall)
+				event.ToolCall = &call
+			}
+
+			if !send(ctx, events, event) {
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+func send(ctx context.Context, events chan<- ai.StreamEvent, event ai.StreamEvent) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// split returns the text up to a trailing unclosed "[" that may begin a placeholder, and that tail
+func split(text string) (string, string) {
+	open := strings.LastIndexByte(text, '[')
+	if open < 0 || strings.IndexByte(text[open:], ']') >= 0 || len(text)-open >= maxPlaceholder {
+		return text, ""
+	}
+	return text[:open], text[open:]
+}
+
+// CurrentCost returns the cost of the wrapped tool
+func (t *Tool) CurrentCost() float64 {
+	return t.tool.CurrentCost()
+}
+
+// redact returns a copy of the request with all text masked
+func (t *Tool) redact(req ai.Request) ai.Request {
+	req.System = t.redactor.Redact(req.System)
+
+	messages := make([]ai.Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		msg.Content = t.redactor.Redact(msg.Content)
+
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]ai.ToolCall, len(msg.ToolCalls))
+			for j, call := range msg.ToolCalls {
+				call.Input = t.redactJSON(call.Input)
+				calls[j] = call
+			}
+			msg.ToolCalls = calls
+		}
+		if len(msg.ToolResults) > 0 {
+			results := make([]ai.ToolResult, len(msg.ToolResults))
+			for j, result := range msg.ToolResults {
+				result.Content = t.redactor.Redact(result.Content)
+				results[j] = result
+			}
+			msg.ToolResults = results
+		}
+
+		messages[i] = msg
+	}
+	req.Messages = messages
+
+	return req
+}
+
+// restore returns a copy of the response with the placeholders replaced by the original values
+func (t *Tool) restore(resp *ai.Response) *ai.Response {
+	restored := *resp
+	restored.Text = t.redactor.Restore(resp.Text)
+
+	if len(resp.ToolCalls) > 0 {
+		restored.ToolCalls = make([]ai.ToolCall, len(resp.ToolCalls))
+		for i, call := range resp.ToolCalls {
+			restored.ToolCalls[i] = t.restoreCall(call)
+		}
+	}
+	return &restored
+}
+
+func (t *Tool) restoreCall(call ai.ToolCall) ai.ToolCall {
+	call.Input = t.rewriteJSON(call.Input, t.redactor.Restore)
+	return call
+}
+
+func (t *Tool) redactJSON(input json.RawMessage) json.RawMessage {
+	return t.rewriteJSON(input, t.redactor.Redact)
+}
+
+// rewriteJSON applies the rewrite to the string values of a JSON document.
+// The input is returned as is when it is not valid JSON or no value changed.
+func (t *Tool) rewriteJSON(input json.RawMessage, rewrite func(string) string) json.RawMessage {
+	var value any
+	if err := json.Unmarshal(input, &value); err != nil {
+		return input
+	}
+
+	changed := false
+	value = rewriteValue(value, func(s string) string {
+		rewritten := rewrite(s)
+		changed = changed || rewritten != s
+		return rewritten
+	})
+	if !changed {
+		return input
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return input
+	}
+	return data
+}
+
+func rewriteValue(value any, rewrite func(string) string) any {
+	switch v := value.(type) {
+	case string:
+		return rewrite(v)
+	case []any:
+		for i := range v {
+			v[i] = rewriteValue(v[i], rewrite)
+		}
+	case map[string]any:
+		for key := range v {
+			v[key] = rewriteValue(v[key], rewrite)
+		}
+	}
+	return value
+}
@@ -0,0 +1,92 @@
+package redact
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dimitarkovachev/eng-assist/pkg/ai"
+)
+
+// echoTool replies with the prompt it received, streamed in small pieces
+type echoTool struct {
+	ai.Tool
+	requests []ai.Request
+}
+
+func (e *echoTool) Complete(ctx context.Context, req ai.Request) (*ai.Response, error) {
+	e.requests = append(e.requests, req)
+	return &ai.Response{
+		Text:      "you said " + req.Messages[0].Content,
+		ToolCalls: []ai.ToolCall{{ID: "1", Name: "search_transcript", Input: json.RawMessage(`{"query":"[EMAIL_1]"}`)}},
+	}, nil
+}
+
+func (e *echoTool) Stream(ctx context.Context, req ai.Request) (<-chan ai.StreamEvent, error) {
+	resp, _ := e.Complete(ctx, req)
+
+	events := make(chan ai.StreamEvent, 32)
+	for i := 0; i < len(resp.Text); i += 4 {
+		events <- ai.StreamEvent{Delta: resp.Text[i:min(i+4, len(resp.Text))]}
+	}
+	events <- ai.StreamEvent{Response: resp}
+	close(events)
+	return events, nil
+}
+
+func TestToolRedactsRequests(t *testing.T) {
+	echo := &echoTool{}
+	tool := NewTool(echo, New(DefaultRules))
+
+	resp, err := tool.Complete(context.Background(), ai.Request{
+		Messages: []ai.Message{
+			{Role: "user", Content: "mail ana@example.com"},
+			{Role: "user", ToolResults: []ai.ToolResult{{CallID: "1", Content: "ana@example.com said hi"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Complete() error: %v", err)
+	}
+
+	sent := echo.requests[0]
+	if sent.Messages[0].Content != "mail [EMAIL_1]" || sent.Messages[1].ToolResults[0].Content != "[EMAIL_1] said hi" {
+		t.Errorf("Expected the request redacted, got: %+v", sent.Messages)
+	}
+	if resp.Text != "you said mail ana@example.com" {
+		t.Errorf("Expected the reply restored, got: %q", resp.Text)
+	}
+	if input := string(resp.ToolCalls[0].Input); input != `{"query":"ana@example.com"}` {
+		t.Errorf("Expected the tool call input restored, got: %s", input)
+	}
+}
+
+func TestToolRestoresSplitPlaceholders(t *testing.T) {
+	tool := NewTool(&echoTool{}, New(DefaultRules))
+
+	events, err := tool.Stream(context.Background(), ai.Request{
+		Messages: []ai.Message{{Role: "user", Content: "ping ana@example.com now"}},
+	})
+	if err != nil {
+		t.Fatalf("Stream() error: %v", err)
+	}
+
+	var text strings.Builder
+	var final *ai.Response
+	for event := range events {
+		if strings.Contains(event.Delta, "[") {
+			t.Errorf("Expected no partial placeholder in a delta, got: %q", event.Delta)
+		}
+		text.WriteString(event.Delta)
+		if event.Response != nil {
+			final = event.Response
+		}
+	}
+
+	if text.String() != "you said ping ana@example.com now" {
+		t.Errorf("Expected the streamed reply restored, got: %q", text.String())
+	}
+	if final == nil || final.Text != text.String() {
+		t.Errorf("Expected the final reply restored, got: %+v", final)
+	}
+}
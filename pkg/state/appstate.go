@@ -1,19 +1,43 @@
 package state
 
-import "sync"
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CostEntry is the cost breakdown of a single AI request
+type CostEntry struct {
+	Model            string    `json:"model"`
+	InputTokens      int       `json:"input_tokens"`
+	OutputTokens     int       `json:"output_tokens"`
+	CacheWriteTokens int       `json:"cache_write_tokens"`
+	CacheReadTokens  int       `json:"cache_read_tokens"`
+	Cost             float64   `json:"cost"`
+	Route            string    `json:"route,omitempty"`
+	Timestamp        time.Time `json:"timestamp"`
+}
 
 type AppState struct {
-	TranscriptState  *TextState
+	TranscriptState  *Transcript
 	AiResponsesState *TextState
 
-	mu       sync.RWMutex
-	cost     float64
-	isPaused bool
+	mu         sync.RWMutex
+	cost       float64
+	ledger     []CostEntry
+	spend      *SpendLog
+	isPaused   bool
+	responding int
+
+	budgetExhausted bool
+	aiError         string
+	queued          int
+	audioError      string
 }
 
 func NewAppState() *AppState {
 	return &AppState{
-		TranscriptState:  New(),
+		TranscriptState:  NewTranscript(),
 		AiResponsesState: New(),
 	}
 }
@@ -26,6 +50,10 @@ func (self *AppState) Clear() {
 	defer self.mu.Unlock()
 
 	self.cost = 0
+	self.responding = 0
+	self.budgetExhausted = false
+	self.aiError = ""
+	self.queued = 0
 }
 
 func (self *AppState) IsPaused() bool {
@@ -51,3 +79,137 @@ func (self *AppState) SetCost(cost float64) {
 	defer self.mu.Unlock()
 	self.cost = cost
 }
+
+// BeginResponse marks that an AI response has started streaming into AiResponsesState.
+// Responses may overlap, the state stays responding until each one has ended.
+func (self *AppState) BeginResponse() {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.responding++
+}
+
+// EndResponse marks that an AI response has finished
+func (self *AppState) EndResponse() {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.responding > 0 {
+		self.responding--
+	}
+}
+
+func (self *AppState) IsResponding() bool {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	return self.responding > 0
+}
+
+// SetSpendLog keeps the daily spend in the log, so DayCost includes earlier runs of the day
+func (self *AppState) SetSpendLog(spend *SpendLog) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.spend = spend
+}
+
+// AddCost records a request in the cost ledger and adds it to the total.
+// A failure to save it to the spend log is shown as an AI error.
+func (self *AppState) AddCost(entry CostEntry) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.cost += entry.Cost
+	self.ledger = append(self.ledger, entry)
+
+	if self.spend != nil {
+		if err := self.spend.Add(entry.Timestamp, entry.Cost); err != nil {
+			self.aiError = fmt.Sprintf("Daily spend not saved: %v", err)
+		}
+	}
+}
+
+// Ledger returns a copy of the per-request cost breakdown.
+// Unlike the total, the ledger survives Clear so no spend goes unaccounted.
+func (self *AppState) Ledger() []CostEntry {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+
+	ledger := make([]CostEntry, len(self.ledger))
+	copy(ledger, self.ledger)
+	return ledger
+}
+
+// SessionCost returns the spend since the session started or was last cleared
+func (self *AppState) SessionCost() float64 {
+	return self.GetCost()
+}
+
+// DayCost returns the spend on the calendar day of day, from the spend log when one is set
+// and otherwise from the ledger of this run
+func (self *AppState) DayCost(day time.Time) float64 {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+
+	if self.spend != nil {
+		return self.spend.Day(day)
+	}
+
+	y, m, d := day.Date()
+	total := 0.0
+	for _, entry := range self.ledger {
+		ey, em, ed := entry.Timestamp.In(day.Location()).Date()
+		if ey == y && em == m && ed == d {
+			total += entry.Cost
+		}
+	}
+	return total
+}
+
+// SetBudgetExhausted marks whether AI requests are stopped by the spending cap
+func (self *AppState) SetBudgetExhausted(exhausted bool) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.budgetExhausted = exhausted
+}
+
+func (self *AppState) IsBudgetExhausted() bool {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	return self.budgetExhausted
+}
+
+// SetAIError records the last AI request failure, an empty message clears it
+func (self *AppState) SetAIError(message string) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.aiError = message
+}
+
+func (self *AppState) GetAIError() string {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	return self.aiError
+}
+
+// SetQueued records how many requests wait for the AI provider to be reachable again
+func (self *AppState) SetQueued(queued int) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.queued = queued
+}
+
+func (self *AppState) Queued() int {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	return self.queued
+}
+
+// SetAudioError records why no audio is captured, the transcript then only takes typed text
+func (self *AppState) SetAudioError(message string) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.audioError = message
+}
+
+func (self *AppState) GetAudioError() string {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	return self.audioError
+}
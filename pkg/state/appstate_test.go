@@ -0,0 +1,90 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAddCost(t *testing.T) {
+	as := NewAppState()
+
+	as.AddCost(CostEntry{Model: "a", Cost: 0.25, Timestamp: time.Now()})
+	as.AddCost(CostEntry{Model: "b", Cost: 0.5, Timestamp: time.Now()})
+
+	if as.GetCost() != 0.75 {
+		t.Errorf("Expected total cost 0.75, got: %f", as.GetCost())
+	}
+
+	ledger := as.Ledger()
+	if len(ledger) != 2 || ledger[0].Model != "a" || ledger[1].Model != "b" {
+		t.Errorf("Expected ledger entries in order, got: %+v", ledger)
+	}
+}
+
+func TestClearKeepsLedger(t *testing.T) {
+	as := NewAppState()
+
+	as.AddCost(CostEntry{Model: "a", Cost: 0.25, Timestamp: time.Now()})
+	as.Clear()
+
+	if as.GetCost() != 0 {
+		t.Errorf("Expected total cost to reset, got: %f", as.GetCost())
+	}
+	if len(as.Ledger()) != 1 {
+		t.Error("Expected ledger to survive Clear")
+	}
+}
+
+func TestDayCost(t *testing.T) {
+	as := NewAppState()
+
+	now := time.Now()
+	as.AddCost(CostEntry{Cost: 1, Timestamp: now.AddDate(0, 0, -1)})
+	as.AddCost(CostEntry{Cost: 0.5, Timestamp: now})
+	as.AddCost(CostEntry{Cost: 0.25, Timestamp: now})
+
+	if cost := as.DayCost(now); cost != 0.75 {
+		t.Errorf("Expected day cost 0.75, got: %f", cost)
+	}
+
+	as.Clear()
+	if cost := as.DayCost(now); cost != 0.75 {
+		t.Errorf("Expected day cost to survive Clear, got: %f", cost)
+	}
+	if cost := as.SessionCost(); cost != 0 {
+		t.Errorf("Expected session cost to reset, got: %f", cost)
+	}
+}
+
+func TestDayCostSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions", "spend.json")
+	now := time.Now()
+
+	spend, err := LoadSpendLog(path)
+	if err != nil {
+		t.Fatalf("LoadSpendLog() error: %v", err)
+	}
+	as := NewAppState()
+	as.SetSpendLog(spend)
+	as.AddCost(CostEntry{Cost: 1, Timestamp: now.AddDate(0, 0, -1)})
+	as.AddCost(CostEntry{Cost: 0.5, Timestamp: now})
+
+	spend, err = LoadSpendLog(path)
+	if err != nil {
+		t.Fatalf("LoadSpendLog() error: %v", err)
+	}
+	restarted := NewAppState()
+	restarted.SetSpendLog(spend)
+	restarted.AddCost(CostEntry{Cost: 0.25, Timestamp: now})
+
+	if cost := restarted.DayCost(now); cost != 0.75 {
+		t.Errorf("Expected day cost 0.75 across restarts, got: %f", cost)
+	}
+	if cost := restarted.DayCost(now.AddDate(0, 0, -1)); cost != 1 {
+		t.Errorf("Expected yesterday's cost 1, got: %f", cost)
+	}
+	if cost := restarted.SessionCost(); cost != 0.25 {
+		t.Errorf("Expected session cost to start over, got: %f", cost)
+	}
+}
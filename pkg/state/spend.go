@@ -0,0 +1,93 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// spendDays bounds how many calendar days the spend log keeps
+const spendDays = 31
+
+// SpendLog keeps the AI spend per calendar day in a JSON file keyed by date,
+// so the daily cap holds across restarts
+type SpendLog struct {
+	path string
+
+	mu   sync.Mutex
+	days map[string]float64
+}
+
+// LoadSpendLog loads the daily spend saved in path.
+// A missing file starts an empty log, an empty path keeps it in memory only.
+func LoadSpendLog(path string) (*SpendLog, error) {
+	l := &SpendLog{path: path, days: make(map[string]float64)}
+	if path == "" {
+		return l, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spend log: %w", err)
+	}
+	if err := json.Unmarshal(data, &l.days); err != nil {
+		return nil, fmt.Errorf("failed to decode spend log %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Add adds the cost to the day of at and saves the log
+func (l *SpendLog) Add(at time.Time, cost float64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.days[dayKey(at)] += cost
+	return l.save(at)
+}
+
+// Day returns the spend on the calendar day of day
+func (l *SpendLog) Day(day time.Time) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.days[dayKey(day)]
+}
+
+// save drops days older than spendDays and writes the log through a temporary file
+func (l *SpendLog) save(now time.Time) error {
+	oldest := dayKey(now.AddDate(0, 0, -spendDays))
+	for key := range l.days {
+		if key < oldest {
+			delete(l.days, key)
+		}
+	}
+
+	if l.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(l.days, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode spend log: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create spend log directory: %w", err)
+	}
+
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write spend log: %w", err)
+	}
+	return os.Rename(tmp, l.path)
+}
+
+// dayKey is the local calendar date of t
+func dayKey(t time.Time) string {
+	return t.Local().Format("2006-01-02")
+}
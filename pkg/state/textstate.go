@@ -8,6 +8,8 @@ import (
 type TextState struct {
 	mu         sync.RWMutex
 	state      string
+	pending    string
+	history    strings.Builder
 	hasNewData bool
 }
 
@@ -22,6 +24,8 @@ func (ts *TextState) Write(txt string) error {
 	defer ts.mu.Unlock()
 
 	ts.state += txt
+	ts.pending += txt
+	ts.history.WriteString(txt)
 	ts.hasNewData = true
 
 	ts.trimToMaxWords(500)
@@ -29,6 +33,13 @@ func (ts *TextState) Write(txt string) error {
 	return nil
 }
 
+// touch marks the state as changed without new text, for transcript segments still being revised
+func (ts *TextState) touch() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.hasNewData = true
+}
+
 func (ts *TextState) Read() (string, bool, error) {
 	ts.mu.Lock()
 	defer ts.mu.Unlock()
@@ -40,6 +51,26 @@ func (ts *TextState) Read() (string, bool, error) {
 	return state, hasNew, nil
 }
 
+// Drain returns the text written since the previous Drain.
+// Unlike the state it is not trimmed, so no words are lost between drains.
+func (ts *TextState) Drain() string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	pending := ts.pending
+	ts.pending = ""
+
+	return pending
+}
+
+// History returns everything written since the last Clear, without trimming
+func (ts *TextState) History() string {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	return ts.history.String()
+}
+
 func (ts *TextState) trimToMaxWords(maxWords int) {
 	words := strings.Fields(ts.state)
 	if len(words) > maxWords {
@@ -59,6 +90,8 @@ func (ts *TextState) Clear() {
 	defer ts.mu.Unlock()
 
 	ts.state = ""
+	ts.pending = ""
+	ts.history.Reset()
 	ts.hasNewData = false
 }
 
@@ -291,3 +291,58 @@ func TestMultipleEmptyReads(t *testing.T) {
 		}
 	}
 }
+
+func TestDrain(t *testing.T) {
+	ts := New()
+
+	ts.Write("hello")
+	ts.Write(" world")
+
+	if pending := ts.Drain(); pending != "hello world" {
+		t.Errorf("Expected 'hello world', got: %s", pending)
+	}
+	if pending := ts.Drain(); pending != "" {
+		t.Errorf("Expected empty pending after drain, got: %s", pending)
+	}
+
+	ts.Write("again")
+	if pending := ts.Drain(); pending != "again" {
+		t.Errorf("Expected 'again', got: %s", pending)
+	}
+
+	state, _ := ts.GetAll()
+	if state != "hello worldagain" {
+		t.Errorf("Drain should not change state, got: %s", state)
+	}
+}
+
+func TestClearDropsPending(t *testing.T) {
+	ts := New()
+
+	ts.Write("hello")
+	ts.Clear()
+
+	if pending := ts.Drain(); pending != "" {
+		t.Errorf("Expected empty pending after clear, got: %s", pending)
+	}
+}
+
+func TestHistoryKeepsTrimmedText(t *testing.T) {
+	ts := New()
+
+	ts.Write("first ")
+	ts.Write(strings.Repeat("word ", 600))
+
+	state, _ := ts.GetAll()
+	if strings.Contains(state, "first") {
+		t.Fatal("Expected the state to be trimmed")
+	}
+	if history := ts.History(); !strings.HasPrefix(history, "first ") || len(strings.Fields(history)) != 601 {
+		t.Errorf("Expected the full history, got %d words", len(strings.Fields(history)))
+	}
+
+	ts.Clear()
+	if history := ts.History(); history != "" {
+		t.Errorf("Expected empty history after clear, got: %s", history)
+	}
+}
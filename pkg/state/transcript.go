@@ -0,0 +1,140 @@
+package state
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source is where a transcript segment came from
+type Source string
+
+const (
+	SourceMic      Source = "mic"
+	SourceLoopback Source = "loopback"
+	SourceTyped    Source = "typed"
+)
+
+// Segment is a piece of the transcript with its timing.
+// Start and End are seconds since the session started, Time is the wall clock at Start.
+// Partial segments are replaced as the transcriber revises them, until one from the same source is final.
+type Segment struct {
+	Start   float64   `json:"start"`
+	End     float64   `json:"end"`
+	Time    time.Time `json:"time"`
+	Source  Source    `json:"source,omitempty"`
+	Speaker string    `json:"speaker,omitempty"`
+	Text    string    `json:"text"`
+	Final   bool      `json:"final"`
+}
+
+// Transcript is the session transcript as a list of segments.
+// The embedded TextState is the text of the final segments, one per line, for the string API.
+type Transcript struct {
+	*TextState
+
+	mu       sync.RWMutex
+	started  time.Time
+	segments []Segment
+	partials map[Source]int
+}
+
+func NewTranscript() *Transcript {
+	return &Transcript{
+		TextState: New(),
+		started:   time.Now(),
+		partials:  map[Source]int{},
+	}
+}
+
+// Add records a segment. A zero Time means now.
+// Start and End may count from anything, they are moved to the session keeping the duration.
+func (t *Transcript) Add(segment Segment) {
+	segment.Text = strings.TrimSpace(segment.Text)
+	if segment.Time.IsZero() {
+		segment.Time = time.Now()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	duration := segment.End - segment.Start
+	segment.Start = segment.Time.Sub(t.started).Seconds()
+	segment.End = segment.Start + duration
+
+	i, revising := t.partials[segment.Source]
+	if revising {
+		t.segments[i] = segment
+	} else {
+		t.segments = append(t.segments, segment)
+		i = len(t.segments) - 1
+	}
+
+	if !segment.Final {
+		t.partials[segment.Source] = i
+		t.TextState.touch()
+		return
+	}
+
+	delete(t.partials, segment.Source)
+	if segment.Text == "" {
+		// Nothing was said after all
+		t.segments = append(t.segments[:i], t.segments[i+1:]...)
+		for source, j := range t.partials {
+			if j > i {
+				t.partials[source] = j - 1
+			}
+		}
+		t.TextState.touch()
+		return
+	}
+	t.TextState.Write(segment.Text + "\n")
+}
+
+// Write adds text as written, keeping it as a final segment for the string API
+func (t *Transcript) Write(txt string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if text := strings.TrimSpace(txt); text != "" {
+		now := time.Now()
+		offset := now.Sub(t.started).Seconds()
+		t.segments = append(t.segments, Segment{Start: offset, End: offset, Time: now, Text: text, Final: true})
+	}
+	return t.TextState.Write(txt)
+}
+
+// Segments returns the segments since the last Clear in the order they started
+func (t *Transcript) Segments() []Segment {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	segments := make([]Segment, len(t.segments))
+	copy(segments, t.segments)
+	return segments
+}
+
+// Partial returns the text still being revised, empty when every segment is final
+func (t *Transcript) Partial() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var texts []string
+	for _, segment := range t.segments {
+		if !segment.Final && segment.Text != "" {
+			texts = append(texts, segment.Text)
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+// Clear drops the segments and starts the session clock over
+func (t *Transcript) Clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.started = time.Now()
+	t.segments = nil
+	t.partials = map[Source]int{}
+	t.TextState.Clear()
+}
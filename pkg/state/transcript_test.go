@@ -0,0 +1,62 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTranscriptRevisesPartials(t *testing.T) {
+	transcript := NewTranscript()
+
+	transcript.Add(Segment{Source: SourceMic, Text: "how do"})
+	transcript.Add(Segment{Source: SourceLoopback, Text: " the deploy is"})
+	transcript.Add(Segment{Source: SourceMic, Text: "how do we roll"})
+
+	if got := transcript.Partial(); got != "how do we roll the deploy is" {
+		t.Errorf("Expected partials in start order, got: %q", got)
+	}
+	if got, _ := transcript.GetAll(); got != "" {
+		t.Errorf("Expected no text before a segment is final, got: %q", got)
+	}
+	if _, hasNew, _ := transcript.Read(); !hasNew {
+		t.Error("Expected partial segments to count as new data")
+	}
+
+	transcript.Add(Segment{Source: SourceMic, Text: "how do we roll back?", Final: true})
+	transcript.Add(Segment{Source: SourceLoopback, Final: true})
+
+	segments := transcript.Segments()
+	if len(segments) != 1 || segments[0].Text != "how do we roll back?" || !segments[0].Final || segments[0].Source != SourceMic {
+		t.Fatalf("Expected the final mic segment only, got: %+v", segments)
+	}
+	if got := transcript.Drain(); got != "how do we roll back?\n" {
+		t.Errorf("Expected the final text to be drained, got: %q", got)
+	}
+	if got := transcript.Partial(); got != "" {
+		t.Errorf("Expected no partials left, got: %q", got)
+	}
+}
+
+func TestTranscriptTiming(t *testing.T) {
+	transcript := NewTranscript()
+	spoken := transcript.started.Add(90 * time.Second)
+
+	transcript.Add(Segment{Start: 12, End: 14.5, Time: spoken, Speaker: "alice", Text: "ship it", Final: true})
+	transcript.Write("typed note")
+
+	segments := transcript.Segments()
+	if len(segments) != 2 {
+		t.Fatalf("Expected 2 segments, got: %+v", segments)
+	}
+	if got := segments[0]; got.Start != 90 || got.End != 92.5 || !got.Time.Equal(spoken) || got.Speaker != "alice" {
+		t.Errorf("Expected the segment moved to the session clock, got: %+v", got)
+	}
+	if got := segments[1]; got.Text != "typed note" || !got.Final || got.Time.IsZero() || got.Start != got.End {
+		t.Errorf("Expected Write to add a final segment, got: %+v", got)
+	}
+
+	transcript.Clear()
+	if len(transcript.Segments()) != 0 || transcript.History() != "" {
+		t.Error("Expected Clear to drop segments and text")
+	}
+}
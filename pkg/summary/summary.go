@@ -0,0 +1,238 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dimitarkovachev/eng-assist/pkg/ai"
+	"github.com/dimitarkovachev/eng-assist/pkg/state"
+)
+
+const (
+	// maxChunkTokens bounds the transcript sent in one request even for very large context windows
+	maxChunkTokens = 20000
+
+	// minChunkTokens keeps chunks useful for small local models
+	minChunkTokens = 1000
+)
+
+const notesPrompt = "You take notes on one part of a long engineering meeting transcript. " +
+	"Write concise notes covering the topics discussed, decisions made and follow-ups with their owners. " +
+	"Keep names, numbers and technical details. Reply with the notes only."
+
+const reportPrompt = `You summarize an engineering meeting from its transcript or from notes on its parts.
+Reply with a single JSON object and nothing else, matching this schema:
+{
+  "tldr": "two or three sentences on what the meeting was about and what came out of it",
+  "topics": ["topic discussed"],
+  "decisions": ["decision made"],
+  "follow_ups": ["follow-up, with its owner when known"]
+}
+Use empty arrays when there is nothing to list.`
+
+// ErrNoTranscript is returned when there is nothing to summarize
+var ErrNoTranscript = errors.New("no transcript to summarize")
+
+// Report is the structured summary of a session
+type Report struct {
+	TLDR      string   `json:"tldr"`
+	Topics    []string `json:"topics"`
+	Decisions []string `json:"decisions"`
+	FollowUps []string `json:"follow_ups"`
+}
+
+// Summarizer writes reports of whole sessions.
+// Transcripts too long for one request are split into chunks, each chunk is turned into notes
+// and the notes are merged the same way until they fit, before the report is written from them.
+type Summarizer struct {
+	tool        ai.Tool
+	appState    *state.AppState
+	chunkTokens int
+	logger      *log.Logger
+}
+
+// New creates a summarizer for a model with the given context window
+func New(tool ai.Tool, appState *state.AppState, contextWindow int, logger *log.Logger) *Summarizer {
+	return &Summarizer{
+		tool:        tool,
+		appState:    appState,
+		chunkTokens: min(max(contextWindow/4, minChunkTokens), maxChunkTokens),
+		logger:      logger,
+	}
+}
+
+// Summarize writes the report of a transcript
+func (s *Summarizer) Summarize(ctx context.Context, transcript string) (*Report, error) {
+	text := strings.TrimSpace(transcript)
+	if text == "" {
+		return nil, ErrNoTranscript
+	}
+
+	for level := 1; ai.EstimateTokens(text) > s.chunkTokens; level++ {
+		notes, err := s.condense(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize level %d: %w", level, err)
+		}
+		if len(notes) >= len(text) {
+			return nil, fmt.Errorf("notes at level %d are not shorter than their input", level)
+		}
+		s.logger.Printf("Summarized %d tokens of transcript into %d tokens of notes", ai.EstimateTokens(text), ai.EstimateTokens(notes))
+		text = notes
+	}
+
+	reply, err := s.complete(ctx, reportPrompt, text, 0)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(reply)
+}
+
+// condense splits the text into chunks and returns the notes on each, in order
+func (s *Summarizer) condense(ctx context.Context, text string) (string, error) {
+	chunks := split(text, s.chunkTokens)
+
+	notes := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		content := fmt.Sprintf("Part %d of %d:\n%s", i+1, len(chunks), chunk)
+		reply, err := s.complete(ctx, notesPrompt, content, max(s.chunkTokens/4, 512))
+		if err != nil {
+			return "", err
+		}
+		notes[i] = strings.TrimSpace(reply)
+	}
+
+	return strings.Join(notes, "\n\n"), nil
+}
+
+// complete sends one request and records its cost
+func (s *Summarizer) complete(ctx context.Context, system string, content string, maxTokens int) (string, error) {
+	resp, err := s.tool.Complete(ctx, ai.Request{
+		System:    system,
+		Messages:  []ai.Message{{Role: "user", Content: content}},
+		MaxTokens: maxTokens,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	s.appState.AddCost(state.CostEntry{
+		Model:            resp.Model,
+		InputTokens:      resp.Usage.InputTokens,
+		OutputTokens:     resp.Usage.OutputTokens,
+		CacheWriteTokens: resp.Usage.CacheWriteTokens,
+		CacheReadTokens:  resp.Usage.CacheReadTokens,
+		Cost:             resp.Cost,
+		Timestamp:        time.Now(),
+	})
+	return resp.Text, nil
+}
+
+// split breaks the text on word boundaries into chunks of at most maxTokens
+func split(text string, maxTokens int) []string {
+	var chunks []string
+	var chunk strings.Builder
+	for _, word := range strings.Fields(text) {
+		if chunk.Len() > 0 && ai.EstimateTokens(chunk.String())+ai.EstimateTokens(" "+word) > maxTokens {
+			chunks = append(chunks, chunk.String())
+			chunk.Reset()
+		}
+		if chunk.Len() > 0 {
+			chunk.WriteByte(' ')
+		}
+		chunk.WriteString(word)
+	}
+	if chunk.Len() > 0 {
+		chunks = append(chunks, chunk.String())
+	}
+	return chunks
+}
+
+// Parse decodes the model reply into a report.
+// Markdown code fences and text around the JSON object are ignored.
+func Parse(reply string) (*Report, error) {
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("summary reply contains no JSON object")
+	}
+
+	var report Report
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &report); err != nil {
+		return nil, fmt.Errorf("summary reply does not match the schema: %w", err)
+	}
+	if strings.TrimSpace(report.TLDR) == "" {
+		return nil, fmt.Errorf("summary reply has no tldr")
+	}
+	return &report, nil
+}
+
+// Markdown renders the report for reading
+func (r *Report) Markdown() string {
+	var md strings.Builder
+	fmt.Fprintf(&md, "# Meeting summary\n\n## TL;DR\n\n%s\n", r.TLDR)
+
+	section := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&md, "\n## %s\n\n", title)
+		for _, item := range items {
+			fmt.Fprintf(&md, "- %s\n", item)
+		}
+	}
+	section("Topics", r.Topics)
+	section("Decisions", r.Decisions)
+	section("Follow-ups", r.FollowUps)
+
+	return md.String()
+}
+
+// Save writes the transcript and, when there is one, the report into the session directory.
+// The transcript is saved even without a report so no session is lost when summarizing fails.
+func Save(dir string, transcript string, report *Report) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "transcript.txt"), []byte(transcript), 0o644); err != nil {
+		return fmt.Errorf("failed to save transcript: %w", err)
+	}
+	if report == nil {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode summary: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "summary.json"), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to save summary: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "summary.md"), []byte(report.Markdown()), 0o644); err != nil {
+		return fmt.Errorf("failed to save summary: %w", err)
+	}
+	return nil
+}
+
+// SaveSegments writes the timed transcript segments into the session directory as transcript.json
+func SaveSegments(dir string, segments []state.Segment) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(segments, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode transcript segments: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "transcript.json"), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to save transcript segments: %w", err)
+	}
+	return nil
+}
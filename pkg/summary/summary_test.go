@@ -0,0 +1,128 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dimitarkovachev/eng-assist/pkg/ai"
+	"github.com/dimitarkovachev/eng-assist/pkg/state"
+)
+
+const reply = `{"tldr":"Planned the rollout.","topics":["rollout"],"decisions":["Ship Monday"],"follow_ups":["Ana updates the runbook"]}`
+
+// notingTool answers note requests with short notes and report requests with the report
+type notingTool struct {
+	ai.Tool
+	requests []ai.Request
+}
+
+func (n *notingTool) Complete(ctx context.Context, req ai.Request) (*ai.Response, error) {
+	n.requests = append(n.requests, req)
+	if req.System == reportPrompt {
+		return &ai.Response{Text: "```json\n" + reply + "\n```", Model: "fake", Cost: 0.1}, nil
+	}
+	return &ai.Response{Text: "notes", Model: "fake", Cost: 0.01}, nil
+}
+
+func TestSummarizeShortTranscript(t *testing.T) {
+	tool := &notingTool{}
+	appState := state.NewAppState()
+	s := New(tool, appState, 8192, log.New(io.Discard, "", 0))
+
+	report, err := s.Summarize(context.Background(), "we ship on monday and ana updates the runbook")
+	if err != nil {
+		t.Fatalf("Summarize() error: %v", err)
+	}
+
+	if len(tool.requests) != 1 {
+		t.Errorf("Expected a single request for a short transcript, got: %d", len(tool.requests))
+	}
+	if report.TLDR != "Planned the rollout." || len(report.FollowUps) != 1 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if len(appState.Ledger()) != 1 {
+		t.Errorf("Expected the request in the ledger, got: %d", len(appState.Ledger()))
+	}
+}
+
+func TestSummarizeLongTranscriptInChunks(t *testing.T) {
+	tool := &notingTool{}
+	s := New(tool, state.NewAppState(), 8192, log.New(io.Discard, "", 0))
+
+	// About 2.5 chunks of transcript
+	transcript := strings.Repeat("deploy ", int(2.5*float64(s.chunkTokens)*4/7))
+
+	if _, err := s.Summarize(context.Background(), transcript); err != nil {
+		t.Fatalf("Summarize() error: %v", err)
+	}
+
+	if len(tool.requests) != 4 {
+		t.Fatalf("Expected 3 note requests and a report, got: %d", len(tool.requests))
+	}
+	for _, req := range tool.requests[:3] {
+		if req.System != notesPrompt || ai.EstimateTokens(req.Messages[0].Content) > s.chunkTokens+10 {
+			t.Errorf("Expected a note request within the chunk size, got %d tokens", ai.EstimateTokens(req.Messages[0].Content))
+		}
+	}
+	if report := tool.requests[3].Messages[0].Content; report != "notes\n\nnotes\n\nnotes" {
+		t.Errorf("Expected the report written from the notes, got: %q", report)
+	}
+}
+
+func TestSummarizeEmptyTranscript(t *testing.T) {
+	s := New(&notingTool{}, state.NewAppState(), 8192, log.New(io.Discard, "", 0))
+
+	if _, err := s.Summarize(context.Background(), "  "); err != ErrNoTranscript {
+		t.Errorf("Expected ErrNoTranscript, got: %v", err)
+	}
+}
+
+func TestParseRejectsMissingTLDR(t *testing.T) {
+	if _, err := Parse(`{"topics":["x"]}`); err == nil {
+		t.Error("Expected an error without a tldr")
+	}
+	if _, err := Parse("no json here"); err == nil {
+		t.Error("Expected an error without JSON")
+	}
+}
+
+func TestSave(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "session")
+	report, _ := Parse(reply)
+
+	if err := Save(dir, "the transcript", report); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	for _, name := range []string{"transcript.txt", "summary.json", "summary.md"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s to be saved: %v", name, err)
+		}
+	}
+
+	md, _ := os.ReadFile(filepath.Join(dir, "summary.md"))
+	if !strings.Contains(string(md), "- Ship Monday") {
+		t.Errorf("Expected decisions in the markdown, got: %s", md)
+	}
+}
+
+func TestSaveSegments(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "session")
+	segments := []state.Segment{{Start: 1.5, End: 3, Source: state.SourceMic, Text: "ship it", Final: true}}
+
+	if err := SaveSegments(dir, segments); err != nil {
+		t.Fatalf("SaveSegments() error: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "transcript.json"))
+	var saved []state.Segment
+	if err := json.Unmarshal(data, &saved); err != nil || len(saved) != 1 || saved[0].Text != "ship it" || saved[0].Start != 1.5 {
+		t.Errorf("Expected the segments to round trip, got: %s (%v)", data, err)
+	}
+}
@@ -0,0 +1,291 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dimitarkovachev/eng-assist/pkg/knowledge"
+	"github.com/dimitarkovachev/eng-assist/pkg/state"
+)
+
+const (
+	// maxMatches bounds the sentences or passages returned by a search
+	maxMatches = 5
+
+	// maxFileBytes bounds the part of a file sent to the model
+	maxFileBytes = 20000
+)
+
+var queryParameters = json.RawMessage(`{
+	"type": "object",
+	"properties": {"query": {"type": "string", "description": "Words to search for"}},
+	"required": ["query"]
+}`)
+
+// SearchTranscript finds the sentences of the session transcript matching a query,
+// including the parts already trimmed from the prompt
+func SearchTranscript(transcript *state.Transcript) Func {
+	return Func{
+		Name:        "search_transcript",
+		Description: "Search everything said in this meeting so far, including parts no longer in the prompt. Returns the best matching sentences in the order they were said, each with the time it was said.",
+		Parameters:  queryParameters,
+		Run: func(ctx context.Context, input json.RawMessage) (string, error) {
+			var args struct {
+				Query string `json:"query"`
+			}
+			if err := decode(input, &args); err != nil {
+				return "", err
+			}
+
+			matches := matchSentences(timedSentences(transcript.Segments()), args.Query, maxMatches)
+			if len(matches) == 0 {
+				return "No matches in the transcript.", nil
+			}
+			return strings.Join(matches, "\n"), nil
+		},
+	}
+}
+
+// matchSentences returns up to limit sentences sharing the most words with the query, in transcript order
+func matchSentences(sentences []string, query string, limit int) []string {
+	terms := words(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	type match struct {
+		position int
+		score    int
+	}
+	var matches []match
+	for i, sentence := range sentences {
+		present := words(sentence)
+		score := 0
+		for term := range terms {
+			if present[term] {
+				score++
+			}
+		}
+		if score > 0 {
+			matches = append(matches, match{position: i, score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].position < matches[j].position })
+
+	result := make([]string, len(matches))
+	for i, m := range matches {
+		result[i] = sentences[m.position]
+	}
+	return result
+}
+
+// timedSentences splits the final segments into sentences, each prefixed with the time its segment was said
+func timedSentences(segments []state.Segment) []string {
+	var text strings.Builder
+	var starts []int
+	var times []time.Time
+	for _, segment := range segments {
+		if !segment.Final {
+			continue
+		}
+		if text.Len() > 0 {
+			text.WriteByte(' ')
+		}
+		starts = append(starts, text.Len())
+		times = append(times, segment.Time)
+		text.WriteString(segment.Text)
+	}
+
+	var result []string
+	current := 0
+	for _, span := range sentenceSpans(text.String()) {
+		for current+1 < len(starts) && starts[current+1] <= span[0] {
+			current++
+		}
+		result = append(result, fmt.Sprintf("[%s] %s", times[current].Format("15:04"), text.String()[span[0]:span[1]]))
+	}
+	return result
+}
+
+// sentenceSpans returns the start and end of each sentence, splitting after sentence punctuation and line breaks
+func sentenceSpans(text string) [][2]int {
+	var result [][2]int
+	add := func(start, end int) {
+		sentence := strings.TrimSpace(text[start:end])
+		if len(sentence) > 1 {
+			start += strings.Index(text[start:end], sentence)
+			result = append(result, [2]int{start, start + len(sentence)})
+		}
+	}
+
+	start := 0
+	for i, r := range text {
+		if r == '.' || r == '?' || r == '!' || r == '\n' {
+			add(start, i+1)
+			start = i + 1
+		}
+	}
+	add(start, len(text))
+	return result
+}
+
+// stopwords are frequent words that would match most sentences
+var stopwords = map[string]bool{
+	"and": true, "are": true, "but": true, "for": true, "how": true, "the": true, "that": true,
+	"this": true, "was": true, "what": true, "who": true, "why": true, "with": true, "you": true,
+}
+
+// words returns the distinct lowercase words of at least three letters, without stopwords
+func words(text string) map[string]bool {
+	result := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) >= 3 && !stopwords[word] {
+			result[word] = true
+		}
+	}
+	return result
+}
+
+// SearchKnowledge searches the indexed team documents
+func SearchKnowledge(index *knowledge.Index) Func {
+	return Func{
+		Name:        "search_knowledge",
+		Description: "Search the team's runbooks, design docs and code. Returns the best matching passages with their path and line range.",
+		Parameters:  queryParameters,
+		Run: func(ctx context.Context, input json.RawMessage) (string, error) {
+			var args struct {
+				Query string `json:"query"`
+			}
+			if err := decode(input, &args); err != nil {
+				return "", err
+			}
+
+			results := index.Search(args.Query, maxMatches)
+			if len(results) == 0 {
+				return "No matching documents.", nil
+			}
+
+			var out strings.Builder
+			for _, result := range results {
+				fmt.Fprintf(&out, "[%s]\n%s\n\n", result.Citation(), result.Text)
+			}
+			return strings.TrimSpace(out.String()), nil
+		},
+	}
+}
+
+// secretExtensions are key and credential stores read_file refuses to open
+var secretExtensions = map[string]bool{
+	".env": true, ".pem": true, ".key": true, ".crt": true, ".p12": true, ".pfx": true,
+	".jks": true, ".keystore": true, ".kdbx": true, ".gpg": true, ".asc": true,
+}
+
+// ReadFile reads a file of the repository under root.
+// Paths are relative and cannot leave root, dotfiles and secret-looking files are refused
+// and long files are cut off.
+func ReadFile(root string) Func {
+	return Func{
+		Name:        "read_file",
+		Description: "Read a file of the team's repository by its path relative to the repository root.",
+		Parameters: json.RawMessage(`{
+	"type": "object",
+	"properties": {"path": {"type": "string", "description": "Relative path of the file, like cmd/server/main.go"}},
+	"required": ["path"]
+}`),
+		Run: func(ctx context.Context, input json.RawMessage) (string, error) {
+			var args struct {
+				Path string `json:"path"`
+			}
+			if err := decode(input, &args); err != nil {
+				return "", err
+			}
+
+			name := filepath.FromSlash(args.Path)
+			if !filepath.IsLocal(name) {
+				return "", fmt.Errorf("path %q is outside the repository", args.Path)
+			}
+			if secretPath(name) {
+				return "", fmt.Errorf("path %q may hold secrets and cannot be read", args.Path)
+			}
+
+			file, err := os.OpenInRoot(root, name)
+			if err != nil {
+				return "", fmt.Errorf("failed to open %s: %w", args.Path, err)
+			}
+			defer file.Close()
+
+			data, err := io.ReadAll(io.LimitReader(file, maxFileBytes+1))
+			if err != nil {
+				return "", fmt.Errorf("failed to read %s: %w", args.Path, err)
+			}
+			if len(data) > maxFileBytes {
+				return string(data[:maxFileBytes]) + "\n[truncated]", nil
+			}
+			return string(data), nil
+		},
+	}
+}
+
+// secretPath reports whether a relative path is a dotfile, lies in a hidden directory
+// like .git or .ssh, or is named like a key or credentials file
+func secretPath(name string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(name), "/") {
+		if strings.HasPrefix(part, ".") && part != "." {
+			return true
+		}
+	}
+
+	base := strings.ToLower(filepath.Base(name))
+	if secretExtensions[filepath.Ext(base)] {
+		return true
+	}
+	for _, prefix := range []string{"id_rsa", "id_dsa", "id_ecdsa", "id_ed25519"} {
+		if strings.HasPrefix(base, prefix) {
+			return true
+		}
+	}
+	return strings.Contains(base, "secret") || strings.Contains(base, "credential") || strings.Contains(base, "password")
+}
+
+// Calculate evaluates arithmetic so the model does not have to
+func Calculate() Func {
+	return Func{
+		Name:        "calculate",
+		Description: "Evaluate an arithmetic expression with + - * / % ^ and parentheses, like (1200 * 0.35) / 60.",
+		Parameters: json.RawMessage(`{
+	"type": "object",
+	"properties": {"expression": {"type": "string"}},
+	"required": ["expression"]
+}`),
+		Run: func(ctx context.Context, input json.RawMessage) (string, error) {
+			var args struct {
+				Expression string `json:"expression"`
+			}
+			if err := decode(input, &args); err != nil {
+				return "", err
+			}
+
+			value, err := Evaluate(args.Expression)
+			if err != nil {
+				return "", err
+			}
+			return strconv.FormatFloat(value, 'f', -1, 64), nil
+		},
+	}
+}
@@ -0,0 +1,160 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Evaluate computes an arithmetic expression.
+// It supports + - * / % ^, unary minus and parentheses with the usual precedence,
+// and ^ is right associative.
+func Evaluate(expression string) (float64, error) {
+	p := &parser{input: expression}
+	value, err := p.sum()
+	if err != nil {
+		return 0, err
+	}
+	if p.skip(); p.pos < len(p.input) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos+1)
+	}
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, fmt.Errorf("result is not a number")
+	}
+	return value, nil
+}
+
+// parser is a recursive descent parser evaluating as it reads
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) skip() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+// next consumes the operator if it is one of ops
+func (p *parser) next(ops string) (byte, bool) {
+	p.skip()
+	if p.pos < len(p.input) && strings.IndexByte(ops, p.input[p.pos]) >= 0 {
+		p.pos++
+		return p.input[p.pos-1], true
+	}
+	return 0, false
+}
+
+// sum = product {("+" | "-") product}
+func (p *parser) sum() (float64, error) {
+	left, err := p.product()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op, ok := p.next("+-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.product()
+		if err != nil {
+			return 0, err
+		}
+		if op == '+' {
+			left += right
+		} else {
+			left -= right
+		}
+	}
+}
+
+// product = unary {("*" | "/" | "%") unary}
+func (p *parser) product() (float64, error) {
+	left, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op, ok := p.next("*/%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case '*':
+			left *= right
+		case '/', '%':
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			if op == '/' {
+				left /= right
+			} else {
+				left = math.Mod(left, right)
+			}
+		}
+	}
+}
+
+// unary = "-" unary | power
+func (p *parser) unary() (float64, error) {
+	if _, ok := p.next("-"); ok {
+		value, err := p.unary()
+		return -value, err
+	}
+	return p.power()
+}
+
+// power = operand ["^" unary]
+func (p *parser) power() (float64, error) {
+	base, err := p.operand()
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := p.next("^"); !ok {
+		return base, nil
+	}
+	exponent, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	return math.Pow(base, exponent), nil
+}
+
+// operand = number | "(" sum ")"
+func (p *parser) operand() (float64, error) {
+	if _, ok := p.next("("); ok {
+		value, err := p.sum()
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := p.next(")"); !ok {
+			return 0, fmt.Errorf("missing closing parenthesis")
+		}
+		return value, nil
+	}
+
+	p.skip()
+	start := p.pos
+	for p.pos < len(p.input) && (p.input[p.pos] >= '0' && p.input[p.pos] <= '9' || p.input[p.pos] == '.') {
+		p.pos++
+	}
+	if start == p.pos {
+		if p.pos >= len(p.input) {
+			return 0, fmt.Errorf("unexpected end of expression")
+		}
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos+1)
+	}
+
+	value, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", p.input[start:p.pos])
+	}
+	return value, nil
+}
@@ -0,0 +1,39 @@
+package tools
+
+import "testing"
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       float64
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 / 4", 2.5},
+		{"10 % 4", 2},
+		{"2 ^ 3 ^ 2", 512},
+		{"-2 ^ 2", -4},
+		{"2 * -3", -6},
+		{"--1", 1},
+		{" 1200 * 0.35 / 60 ", 7},
+	}
+
+	for _, tt := range tests {
+		got, err := Evaluate(tt.expression)
+		if err != nil {
+			t.Errorf("Evaluate(%q) error: %v", tt.expression, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Evaluate(%q) = %v, want %v", tt.expression, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	for _, expression := range []string{"", "1 +", "(1 + 2", "1 / 0", "2 $ 3", "1.2.3", "1 2"} {
+		if _, err := Evaluate(expression); err == nil {
+			t.Errorf("Evaluate(%q) expected an error", expression)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/dimitarkovachev/eng-assist/pkg/ai"
+)
+
+// Func is a Go function the model may call
+type Func struct {
+	Name        string
+	Description string
+
+	// Parameters is the JSON schema of the input object
+	Parameters json.RawMessage
+
+	Run func(ctx context.Context, input json.RawMessage) (string, error)
+}
+
+// Registry holds the functions offered to the model, it implements ai.Toolbox
+type Registry struct {
+	mu    sync.RWMutex
+	funcs map[string]Func
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{funcs: make(map[string]Func)}
+}
+
+// Register adds the function, replacing any function with the same name
+func (r *Registry) Register(fn Func) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.funcs[fn.Name] = fn
+}
+
+// Len returns the number of registered functions
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.funcs)
+}
+
+// Specs describes the registered functions sorted by name
+func (r *Registry) Specs() []ai.ToolSpec {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	specs := make([]ai.ToolSpec, 0, len(r.funcs))
+	for _, fn := range r.funcs {
+		specs = append(specs, ai.ToolSpec{Name: fn.Name, Description: fn.Description, Parameters: fn.Parameters})
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+// Call runs the named function
+func (r *Registry) Call(ctx context.Context, name string, input json.RawMessage) (string, error) {
+	r.mu.RLock()
+	fn, ok := r.funcs[name]
+	r.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q", name)
+	}
+	return fn.Run(ctx, input)
+}
+
+// decode reads the input object of a call into v
+func decode(input json.RawMessage, v any) error {
+	if err := json.Unmarshal(input, v); err != nil {
+		return fmt.Errorf("invalid input: %w", err)
+	}
+	return nil
+}
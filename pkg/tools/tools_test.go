@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dimitarkovachev/eng-assist/pkg/knowledge"
+	"github.com/dimitarkovachev/eng-assist/pkg/state"
+)
+
+func call(t *testing.T, registry *Registry, name string, input string) (string, error) {
+	t.Helper()
+	return registry.Call(context.Background(), name, json.RawMessage(input))
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(Calculate())
+	registry.Register(ReadFile(t.TempDir()))
+
+	specs := registry.Specs()
+	if len(specs) != 2 || specs[0].Name != "calculate" || specs[1].Name != "read_file" {
+		t.Fatalf("Expected specs sorted by name, got: %+v", specs)
+	}
+	if !json.Valid(specs[0].Parameters) {
+		t.Errorf("Expected a valid JSON schema, got: %s", specs[0].Parameters)
+	}
+
+	if out, err := call(t, registry, "calculate", `{"expression":"6*7"}`); err != nil || out != "42" {
+		t.Errorf("Expected 42, got %q, %v", out, err)
+	}
+	if _, err := call(t, registry, "missing", `{}`); err == nil {
+		t.Error("Expected an error for an unknown tool")
+	}
+	if _, err := call(t, registry, "calculate", `not json`); err == nil {
+		t.Error("Expected an error for invalid input")
+	}
+}
+
+func TestSearchTranscript(t *testing.T) {
+	transcript := state.NewTranscript()
+	at := func(clock string) time.Time {
+		t, _ := time.Parse("15:04", clock)
+		return t
+	}
+	transcript.Add(state.Segment{Time: at("14:30"), Text: "We moved the deploy to Friday. Lunch was", Final: true})
+	transcript.Add(state.Segment{Time: at("14:31"), Text: "good. The rollback plan needs an owner? Ana owns", Final: true})
+	transcript.Add(state.Segment{Time: at("14:32"), Text: "the deploy runbook.", Final: true})
+	transcript.Add(state.Segment{Time: at("14:33"), Text: "deploy runbook deploy", Final: false})
+
+	registry := NewRegistry()
+	registry.Register(SearchTranscript(transcript))
+
+	out, err := call(t, registry, "search_transcript", `{"query":"who owns the deploy"}`)
+	if err != nil {
+		t.Fatalf("search_transcript error: %v", err)
+	}
+	if out != "[14:30] We moved the deploy to Friday.\n[14:31] Ana owns the deploy runbook." {
+		t.Errorf("Expected the matching sentences in order, got: %q", out)
+	}
+
+	if out, _ := call(t, registry, "search_transcript", `{"query":"kubernetes"}`); !strings.HasPrefix(out, "No matches") {
+		t.Errorf("Expected no matches, got: %q", out)
+	}
+}
+
+func TestSearchKnowledge(t *testing.T) {
+	index, err := knowledge.Build("../knowledge/testdata/docs")
+	if err != nil {
+		t.Fatalf("Build() error: %v", err)
+	}
+
+	registry := NewRegistry()
+	registry.Register(SearchKnowledge(index))
+
+	out, err := call(t, registry, "search_knowledge", `{"query":"roll back a deployment"}`)
+	if err != nil {
+		t.Fatalf("search_knowledge error: %v", err)
+	}
+	if !strings.HasPrefix(out, "[runbooks/deploy.md:") {
+		t.Errorf("Expected the runbook cited first, got: %q", out)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "docs"), 0o755)
+	os.WriteFile(filepath.Join(root, "docs", "notes.md"), []byte("hello"), 0o644)
+	os.WriteFile(filepath.Join(root, "big.txt"), []byte(strings.Repeat("x", maxFileBytes+10)), 0o644)
+	os.WriteFile(filepath.Join(filepath.Dir(root), "secret.txt"), []byte("secret"), 0o644)
+
+	registry := NewRegistry()
+	registry.Register(ReadFile(root))
+
+	if out, err := call(t, registry, "read_file", `{"path":"docs/notes.md"}`); err != nil || out != "hello" {
+		t.Errorf("Expected the file contents, got %q, %v", out, err)
+	}
+	if out, _ := call(t, registry, "read_file", `{"path":"big.txt"}`); !strings.HasSuffix(out, "[truncated]") || len(out) > maxFileBytes+20 {
+		t.Errorf("Expected a truncated file, got %d bytes", len(out))
+	}
+	for _, path := range []string{"../secret.txt", "/etc/passwd", "missing.txt"} {
+		if _, err := call(t, registry, "read_file", `{"path":"`+path+`"}`); err == nil {
+			t.Errorf("Expected an error reading %s", path)
+		}
+	}
+
+	for _, path := range []string{".env", "config/.ssh/config", "deploy/server.pem", "id_rsa.pub", "config/credentials.json", "prod.env"} {
+		os.MkdirAll(filepath.Dir(filepath.Join(root, path)), 0o755)
+		os.WriteFile(filepath.Join(root, path), []byte("secret"), 0o644)
+		if out, err := call(t, registry, "read_file", `{"path":"`+path+`"}`); err == nil {
+			t.Errorf("Expected reading %s to be refused, got: %q", path, out)
+		}
+	}
+}
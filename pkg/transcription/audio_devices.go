@@ -1,42 +1,141 @@
 package transcription
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"strconv"
+	"strings"
 
+	"github.com/dimitarkovachev/eng-assist/pkg/state"
 	"github.com/veandco/go-sdl2/sdl"
 )
 
-func GetDeviceIDByName(name string) int {
-	// Initialize SDL2 Audio subsystem
-	if err := sdl.Init(sdl.INIT_AUDIO); err != nil {
-		log.Fatalf("Failed to initialize SDL: %v", err)
+// ErrNoAudio is returned when no audio can be captured, because SDL failed or found no devices
+var ErrNoAudio = errors.New("audio capture unavailable")
+
+// Device is an audio capture device as numbered by SDL, which is how whisper selects it
+type Device struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+}
+
+// loopbackNames are parts of the names of virtual devices capturing what the computer plays
+var loopbackNames = []string{"cable", "loopback", "blackhole", "soundflower", "monitor of", "stereo mix"}
+
+// Source guesses from the name whether the device is a microphone or a loopback of the computer's output
+func (d Device) Source() state.Source {
+	name := strings.ToLower(d.Name)
+	for _, loopback := range loopbackNames {
+		if strings.Contains(name, loopback) {
+			return state.SourceLoopback
+		}
 	}
-	defer sdl.Quit()
+	return state.SourceMic
+}
+
+// DefaultDevice lets whisper capture from the system default input
+var DefaultDevice = Device{Index: -1, Name: "default"}
+
+// DeviceError reports a device spec matching no capture device, or several when Ambiguous
+type DeviceError struct {
+	Spec      string
+	Devices   []Device
+	Ambiguous bool
+}
 
-	// Get number of capture devices (true = capture devices, false = playback devices)
-	numDevices := sdl.GetNumAudioDevices(true)
+func (e *DeviceError) Error() string {
+	if e.Ambiguous {
+		return fmt.Sprintf("capture device %q is ambiguous, %s", e.Spec, available(e.Devices))
+	}
+	if _, err := strconv.Atoi(e.Spec); err == nil {
+		return fmt.Sprintf("no capture device #%s, %s", e.Spec, available(e.Devices))
+	}
+	return fmt.Sprintf("no capture device named %q, %s", e.Spec, available(e.Devices))
+}
+
+// DeviceLister discovers audio capture devices
+type DeviceLister interface {
+	CaptureDevices() ([]Device, error)
+}
+
+// SDLDevices lists the capture devices SDL can open
+type SDLDevices struct{}
+
+// CaptureDevices returns the devices in SDL order, errors wrap ErrNoAudio
+func (SDLDevices) CaptureDevices() ([]Device, error) {
+	if err := audio.InitSubSystem(sdl.INIT_AUDIO); err != nil {
+		return nil, fmt.Errorf("%w: failed to initialize SDL audio: %w", ErrNoAudio, err)
+	}
+	defer audio.QuitSubSystem(sdl.INIT_AUDIO)
 
+	// true lists capture devices, false would list playback devices
+	numDevices := audio.GetNumAudioDevices(true)
 	if numDevices < 0 {
-		log.Fatalf("Failed to get audio devices: %v", sdl.GetError())
+		return nil, fmt.Errorf("%w: failed to list audio devices: %w", ErrNoAudio, audio.GetError())
 	}
 
-	fmt.Printf("init: found %d capture devices:\n", numDevices)
+	devices := make([]Device, numDevices)
+	for i := range devices {
+		devices[i] = Device{Index: i, Name: audio.GetAudioDeviceName(i, true)}
+	}
+	return devices, nil
+}
 
-	deviceID := 999
-	// List all capture devices
-	for i := 0; i < numDevices; i++ {
-		deviceName := sdl.GetAudioDeviceName(i, true) // true = capture
-		if deviceName != "" {
-			fmt.Printf("init:    - Capture device #%d: '%s'\n", i, deviceName)
-		} else {
-			fmt.Printf("init:    - Capture device #%d: (unknown)\n", i)
+// SelectDevice lists the capture devices and picks the one given by spec, see FindDevice.
+// It returns ErrNoAudio when there is nothing to capture from.
+func SelectDevice(lister DeviceLister, spec string) (Device, error) {
+	devices, err := lister.CaptureDevices()
+	if err != nil {
+		return Device{}, err
+	}
+	if len(devices) == 0 {
+		return Device{}, fmt.Errorf("%w: no capture devices found", ErrNoAudio)
+	}
+	return FindDevice(devices, spec)
+}
+
+// FindDevice picks the device given by index or name, an empty spec picks DefaultDevice.
+// A name matches exactly or, case-insensitively, as part of a single device name.
+func FindDevice(devices []Device, spec string) (Device, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return DefaultDevice, nil
+	}
+
+	if index, err := strconv.Atoi(spec); err == nil {
+		for _, device := range devices {
+			if device.Index == index {
+				return device, nil
+			}
 		}
-		if deviceName == name {
-			deviceID = i
+		return Device{}, &DeviceError{Spec: spec, Devices: devices}
+	}
+
+	var matches []Device
+	for _, device := range devices {
+		if device.Name == spec {
+			return device, nil
+		}
+		if strings.Contains(strings.ToLower(device.Name), strings.ToLower(spec)) {
+			matches = append(matches, device)
 		}
 	}
 
-	fmt.Printf("init: found deviceID: %d\n", deviceID)
-	return deviceID
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	return Device{}, &DeviceError{Spec: spec, Devices: devices, Ambiguous: len(matches) > 1}
+}
+
+// available describes the devices for error messages
+func available(devices []Device) string {
+	if len(devices) == 0 {
+		return "no capture devices found"
+	}
+
+	names := make([]string, len(devices))
+	for i, device := range devices {
+		names[i] = fmt.Sprintf("#%d %q", device.Index, device.Name)
+	}
+	return "available: " + strings.Join(names, ", ")
 }
@@ -0,0 +1,112 @@
+package transcription
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dimitarkovachev/eng-assist/pkg/state"
+)
+
+// fakeDevices is a DeviceLister returning fixed devices or an error
+type fakeDevices struct {
+	devices []Device
+	err     error
+}
+
+func (f fakeDevices) CaptureDevices() ([]Device, error) {
+	return f.devices, f.err
+}
+
+func TestFindDevice(t *testing.T) {
+	devices := []Device{
+		{Index: 0, Name: "MacBook Pro Microphone"},
+		{Index: 1, Name: "VB-Cable"},
+		{Index: 2, Name: "VB-Cable A"},
+	}
+
+	tests := []struct {
+		spec    string
+		want    Device
+		wantErr string
+	}{
+		{"", DefaultDevice, ""},
+		{"1", devices[1], ""},
+		{"VB-Cable", devices[1], ""},
+		{"macbook", devices[0], ""},
+		{"cable", Device{}, "ambiguous"},
+		{"Yeti", Device{}, `no capture device named "Yeti", available: #0 "MacBook Pro Microphone"`},
+		{"7", Device{}, "no capture device #7"},
+	}
+
+	for _, tt := range tests {
+		got, err := FindDevice(devices, tt.spec)
+		if tt.wantErr != "" {
+			var deviceErr *DeviceError
+			if !errors.As(err, &deviceErr) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("FindDevice(%q) error = %v, want DeviceError %q", tt.spec, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("FindDevice(%q) = %+v, %v, want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestSelectDevice(t *testing.T) {
+	mic := Device{Index: 0, Name: "USB Microphone"}
+	sdlErr := fmt.Errorf("%w: failed to initialize SDL audio", ErrNoAudio)
+
+	tests := []struct {
+		name      string
+		lister    fakeDevices
+		spec      string
+		want      Device
+		wantNoMic bool
+	}{
+		{"default", fakeDevices{devices: []Device{mic}}, "", DefaultDevice, false},
+		{"by name", fakeDevices{devices: []Device{mic}}, "usb", mic, false},
+		{"sdl failure", fakeDevices{err: sdlErr}, "", Device{}, true},
+		{"no devices", fakeDevices{}, "usb", Device{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := SelectDevice(tt.lister, tt.spec)
+		if tt.wantNoMic {
+			if !errors.Is(err, ErrNoAudio) {
+				t.Errorf("%s: expected ErrNoAudio, got: %v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: SelectDevice() = %+v, %v, want %+v", tt.name, got, err, tt.want)
+		}
+	}
+
+	_, err := SelectDevice(fakeDevices{devices: []Device{mic}}, "Yeti")
+	var deviceErr *DeviceError
+	if !errors.As(err, &deviceErr) || errors.Is(err, ErrNoAudio) {
+		t.Errorf("Expected a DeviceError for an unknown device, got: %v", err)
+	}
+}
+
+func TestDeviceSource(t *testing.T) {
+	tests := []struct {
+		name string
+		want state.Source
+	}{
+		{"MacBook Pro Microphone", state.SourceMic},
+		{"VB-Cable", state.SourceLoopback},
+		{"BlackHole 2ch", state.SourceLoopback},
+		{"Monitor of Built-in Audio Analog Stereo", state.SourceLoopback},
+		{"default", state.SourceMic},
+	}
+
+	for _, tt := range tests {
+		if got := (Device{Name: tt.name}).Source(); got != tt.want {
+			t.Errorf("Device{%q}.Source() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
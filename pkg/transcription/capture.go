@@ -0,0 +1,83 @@
+package transcription
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// SampleRate is the rate whisper expects, audio is captured as mono 16-bit PCM at this rate
+const SampleRate = 16000
+
+// captureFrames is the SDL buffer size in sample frames
+const captureFrames = 1024
+
+// Capture reads PCM audio from an SDL capture device
+type Capture struct {
+	mu     sync.Mutex
+	dev    sdl.AudioDeviceID
+	closed bool
+}
+
+// OpenCapture starts capturing from the device, DefaultDevice captures from the system input
+func OpenCapture(device Device) (*Capture, error) {
+	if err := audio.InitSubSystem(sdl.INIT_AUDIO); err != nil {
+		return nil, fmt.Errorf("%w: failed to initialize SDL audio: %w", ErrNoAudio, err)
+	}
+
+	// An empty name opens the default device
+	name := ""
+	if device != DefaultDevice {
+		name = device.Name
+	}
+
+	desired := sdl.AudioSpec{
+		Freq:     SampleRate,
+		Format:   sdl.AUDIO_S16LSB,
+		Channels: 1,
+		Samples:  captureFrames,
+	}
+	dev, err := audio.OpenAudioDevice(name, true, &desired, nil, 0)
+	if err != nil {
+		audio.QuitSubSystem(sdl.INIT_AUDIO)
+		return nil, fmt.Errorf("failed to open capture device %q: %w", device.Name, err)
+	}
+
+	audio.PauseAudioDevice(dev, false)
+	return &Capture{dev: dev}, nil
+}
+
+// Read blocks until captured audio is available, returning io.EOF once closed
+func (c *Capture) Read(p []byte) (int, error) {
+	for {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return 0, io.EOF
+		}
+		n, err := audio.DequeueAudio(c.dev, p)
+		c.mu.Unlock()
+
+		if err != nil || n > 0 {
+			return n, err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Close stops capturing
+func (c *Capture) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	audio.CloseAudioDevice(c.dev)
+	audio.QuitSubSystem(sdl.INIT_AUDIO)
+	return nil
+}
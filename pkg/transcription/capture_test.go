@@ -0,0 +1,100 @@
+package transcription
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// fakeSDL reference counts the audio subsystem like SDL, closing open devices when it shuts down
+type fakeSDL struct {
+	mu      sync.Mutex
+	inits   int
+	devices []string
+	open    map[sdl.AudioDeviceID]bool
+	next    sdl.AudioDeviceID
+}
+
+func newFakeSDL(t *testing.T, devices ...string) *fakeSDL {
+	fake := &fakeSDL{devices: devices, open: map[sdl.AudioDeviceID]bool{}}
+	previous := audio
+	audio = fake
+	t.Cleanup(func() { audio = previous })
+	return fake
+}
+
+func (f *fakeSDL) InitSubSystem(flags uint32) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inits++
+	return nil
+}
+
+func (f *fakeSDL) QuitSubSystem(flags uint32) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inits--
+	if f.inits == 0 {
+		f.open = map[sdl.AudioDeviceID]bool{}
+	}
+}
+
+func (f *fakeSDL) GetError() error { return errors.New("fake SDL error") }
+
+func (f *fakeSDL) GetNumAudioDevices(isCapture bool) int { return len(f.devices) }
+
+func (f *fakeSDL) GetAudioDeviceName(index int, isCapture bool) string { return f.devices[index] }
+
+func (f *fakeSDL) OpenAudioDevice(device string, isCapture bool, desired, obtained *sdl.AudioSpec, allowedChanges int) (sdl.AudioDeviceID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.inits == 0 {
+		return 0, errors.New("audio subsystem not initialized")
+	}
+	f.next++
+	f.open[f.next] = true
+	return f.next, nil
+}
+
+func (f *fakeSDL) PauseAudioDevice(dev sdl.AudioDeviceID, pauseOn bool) {}
+
+func (f *fakeSDL) DequeueAudio(dev sdl.AudioDeviceID, data []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.open[dev] {
+		return 0, errors.New("invalid audio device")
+	}
+	return len(data), nil
+}
+
+func (f *fakeSDL) CloseAudioDevice(dev sdl.AudioDeviceID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.open, dev)
+}
+
+func TestListDevicesDuringCapture(t *testing.T) {
+	fake := newFakeSDL(t, "USB Microphone")
+
+	capture, err := OpenCapture(DefaultDevice)
+	if err != nil {
+		t.Fatalf("OpenCapture() error: %v", err)
+	}
+
+	devices, err := SDLDevices{}.CaptureDevices()
+	if err != nil || len(devices) != 1 || devices[0].Name != "USB Microphone" {
+		t.Fatalf("CaptureDevices() = %+v, %v", devices, err)
+	}
+
+	buf := make([]byte, 64)
+	if n, err := capture.Read(buf); err != nil || n != len(buf) {
+		t.Fatalf("Expected the capture to survive listing devices, got: %d, %v", n, err)
+	}
+
+	capture.Close()
+	if fake.inits != 0 {
+		t.Errorf("Expected every audio subsystem init to be released, %d left", fake.inits)
+	}
+}
@@ -0,0 +1,19 @@
+package transcription
+
+import (
+	"context"
+)
+
+// NoAudio is the Transcriptor used when no audio can be captured.
+// The transcript then only grows from text typed into the UI.
+type NoAudio struct{}
+
+// Start does nothing, there is no device to capture from
+func (NoAudio) Start(ctx context.Context) error {
+	return nil
+}
+
+// Stop does nothing
+func (NoAudio) Stop() error {
+	return nil
+}
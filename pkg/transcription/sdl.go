@@ -0,0 +1,49 @@
+package transcription
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// sdlAudio is the part of SDL used to list and capture audio devices, replaced in tests.
+// The audio subsystem is reference counted: every InitSubSystem is paired with a QuitSubSystem,
+// and sdl.Quit is never called, as it would close devices opened elsewhere in the process.
+type sdlAudio interface {
+	InitSubSystem(flags uint32) error
+	QuitSubSystem(flags uint32)
+	GetError() error
+	GetNumAudioDevices(isCapture bool) int
+	GetAudioDeviceName(index int, isCapture bool) string
+	OpenAudioDevice(device string, isCapture bool, desired, obtained *sdl.AudioSpec, allowedChanges int) (sdl.AudioDeviceID, error)
+	PauseAudioDevice(dev sdl.AudioDeviceID, pauseOn bool)
+	DequeueAudio(dev sdl.AudioDeviceID, data []byte) (int, error)
+	CloseAudioDevice(dev sdl.AudioDeviceID)
+}
+
+var audio sdlAudio = libSDL{}
+
+// libSDL calls the SDL library
+type libSDL struct{}
+
+func (libSDL) InitSubSystem(flags uint32) error { return sdl.InitSubSystem(flags) }
+func (libSDL) QuitSubSystem(flags uint32)       { sdl.QuitSubSystem(flags) }
+func (libSDL) GetError() error                  { return sdl.GetError() }
+
+func (libSDL) GetNumAudioDevices(isCapture bool) int { return sdl.GetNumAudioDevices(isCapture) }
+
+func (libSDL) GetAudioDeviceName(index int, isCapture bool) string {
+	return sdl.GetAudioDeviceName(index, isCapture)
+}
+
+func (libSDL) OpenAudioDevice(device string, isCapture bool, desired, obtained *sdl.AudioSpec, allowedChanges int) (sdl.AudioDeviceID, error) {
+	return sdl.OpenAudioDevice(device, isCapture, desired, obtained, allowedChanges)
+}
+
+func (libSDL) PauseAudioDevice(dev sdl.AudioDeviceID, pauseOn bool) {
+	sdl.PauseAudioDevice(dev, pauseOn)
+}
+
+func (libSDL) DequeueAudio(dev sdl.AudioDeviceID, data []byte) (int, error) {
+	return sdl.DequeueAudio(dev, data)
+}
+
+func (libSDL) CloseAudioDevice(dev sdl.AudioDeviceID) { sdl.CloseAudioDevice(dev) }
@@ -0,0 +1,151 @@
+package transcription
+
+import (
+	"regexp"
+	"strings"
+)
+
+// StreamSegment is text recognized by whisper-stream.
+// Partial segments are replaced by the next segment, final ones are complete lines.
+// A final segment without text retracts a partial that turned out to be no speech.
+type StreamSegment struct {
+	Text  string
+	Final bool
+}
+
+var (
+	// timestampRe matches the time range whisper-stream prints before lines in VAD mode
+	timestampRe = regexp.MustCompile(`^\[\d{2}:\d{2}:\d{2}\.\d{3} --> \d{2}:\d{2}:\d{2}\.\d{3}\]`)
+
+	// annotationRe matches non-speech markers like [BLANK_AUDIO], [Start speaking] or (music)
+	annotationRe = regexp.MustCompile(`\[[^\]]*\]|^\s*\([^)]*\)\s*$|^\s*\*[^*]*\*\s*$`)
+)
+
+// logPrefixes start the lines whisper-stream logs while loading and between transcriptions
+var logPrefixes = []string{
+	"whisper_", "ggml_", "main:", "init:", "system_info:", "load_backend:", "###",
+}
+
+// StreamParser turns whisper-stream terminal output into segments.
+// whisper-stream rewrites the current line with "\x1b[2K\r" as it refines the text
+// and ends it with a newline once final, which a pty turns into "\r\n".
+type StreamParser struct {
+	line    []byte
+	escape  []byte
+	cr      bool
+	partial string
+}
+
+// NewStreamParser creates a parser at the start of the stream
+func NewStreamParser() *StreamParser {
+	return &StreamParser{}
+}
+
+// Feed parses the next bytes of output, which may end in the middle of a line or escape sequence
+func (p *StreamParser) Feed(data []byte) []StreamSegment {
+	var segments []StreamSegment
+	for _, b := range data {
+		if p.escape != nil {
+			p.escape = append(p.escape, b)
+			if p.escapeDone() {
+				p.handleEscape(&segments)
+			}
+			continue
+		}
+
+		// A carriage return rewrites the line, unless it is the pty's line ending
+		if p.cr {
+			p.cr = false
+			if b != '\n' {
+				p.rewrite(&segments)
+			}
+		}
+
+		switch b {
+		case '\x1b':
+			p.escape = []byte{b}
+		case '\r':
+			p.cr = true
+		case '\n':
+			p.finish(&segments)
+		case '\t':
+			p.line = append(p.line, ' ')
+		default:
+			if b < 0x20 || b == 0x7f {
+				// Other control characters such as bells carry no text
+				continue
+			}
+			p.line = append(p.line, b)
+		}
+	}
+
+	p.emitPartial(&segments)
+	return segments
+}
+
+// Flush ends the stream, the unfinished line becomes final
+func (p *StreamParser) Flush() []StreamSegment {
+	var segments []StreamSegment
+	p.escape = nil
+	p.cr = false
+	p.finish(&segments)
+	return segments
+}
+
+// escapeDone reports whether the buffered escape sequence is complete
+func (p *StreamParser) escapeDone() bool {
+	if len(p.escape) == 2 {
+		// Only CSI sequences continue after ESC [
+		return p.escape[1] != '['
+	}
+	last := p.escape[len(p.escape)-1]
+	return len(p.escape) > 2 && last >= 0x40 && last <= 0x7e
+}
+
+// handleEscape applies a complete escape sequence, only clearing the line matters and the rest is dropped
+func (p *StreamParser) handleEscape(segments *[]StreamSegment) {
+	if string(p.escape) == "\x1b[2K" {
+		p.rewrite(segments)
+	}
+	p.escape = nil
+}
+
+// rewrite starts the line over, keeping what it said as a partial segment
+func (p *StreamParser) rewrite(segments *[]StreamSegment) {
+	p.emitPartial(segments)
+	p.line = p.line[:0]
+}
+
+// emitPartial reports the current line when it changed since the last partial segment
+func (p *StreamParser) emitPartial(segments *[]StreamSegment) {
+	text := cleanText(string(p.line))
+	if text == "" || text == p.partial {
+		return
+	}
+	p.partial = text
+	*segments = append(*segments, StreamSegment{Text: text})
+}
+
+// finish ends the line, reporting it as a final segment unless it holds no speech.
+// A partial already reported for a line without speech is retracted with an empty final.
+func (p *StreamParser) finish(segments *[]StreamSegment) {
+	if text := cleanText(string(p.line)); text != "" || p.partial != "" {
+		*segments = append(*segments, StreamSegment{Text: text, Final: true})
+	}
+	p.line = p.line[:0]
+	p.partial = ""
+}
+
+// cleanText returns the speech in a line of whisper output, empty for banners, logs and non-speech markers
+func cleanText(line string) string {
+	line = strings.TrimSpace(line)
+	for _, prefix := range logPrefixes {
+		if strings.HasPrefix(line, prefix) {
+			return ""
+		}
+	}
+
+	line = timestampRe.ReplaceAllString(line, "")
+	line = annotationRe.ReplaceAllString(line, "")
+	return strings.Join(strings.Fields(line), " ")
+}
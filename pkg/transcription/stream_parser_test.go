@@ -0,0 +1,192 @@
+package transcription
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseAll feeds the chunks in order and flushes, returning every segment
+func parseAll(chunks ...string) []StreamSegment {
+	parser := NewStreamParser()
+	var segments []StreamSegment
+	for _, chunk := range chunks {
+		segments = append(segments, parser.Feed([]byte(chunk))...)
+	}
+	return append(segments, parser.Flush()...)
+}
+
+// finals returns the text of the final segments, leaving out retracted partials
+func finals(segments []StreamSegment) []string {
+	var texts []string
+	for _, segment := range segments {
+		if segment.Final && segment.Text != "" {
+			texts = append(texts, segment.Text)
+		}
+	}
+	return texts
+}
+
+// unresolved reports whether the last partial segment is not followed by a final one
+func unresolved(segments []StreamSegment) bool {
+	return len(segments) > 0 && !segments[len(segments)-1].Final
+}
+
+func partial(text string) StreamSegment { return StreamSegment{Text: text} }
+func final(text string) StreamSegment   { return StreamSegment{Text: text, Final: true} }
+
+func TestStreamParser(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		want   []StreamSegment
+	}{
+		{
+			name:   "plain lines",
+			chunks: []string{"hello world\nsecond line\n"},
+			want:   []StreamSegment{final("hello world"), final("second line")},
+		},
+		{
+			name:   "pty line endings",
+			chunks: []string{"hello world\r\n"},
+			want:   []StreamSegment{final("hello world")},
+		},
+		{
+			name:   "clear line rewrites",
+			chunks: []string{"\x1b[2K\r So the", "\x1b[2K\r So the deploy", "\x1b[2K\r So the deploy failed.\r\n"},
+			want:   []StreamSegment{partial("So the"), partial("So the deploy"), final("So the deploy failed.")},
+		},
+		{
+			name:   "carriage return rewrites",
+			chunks: []string{"first try\rsecond try\n"},
+			want:   []StreamSegment{partial("first try"), final("second try")},
+		},
+		{
+			name:   "unchanged rewrite is not repeated",
+			chunks: []string{"\x1b[2K\r same", "\x1b[2K\r same", "\n"},
+			want:   []StreamSegment{partial("same"), final("same")},
+		},
+		{
+			name:   "escape split across reads",
+			chunks: []string{"old\x1b[", "2K\rnew\r", "\n"},
+			want:   []StreamSegment{partial("old"), partial("new"), final("new")},
+		},
+		{
+			name:   "crlf split across reads",
+			chunks: []string{"text\r", "\n"},
+			want:   []StreamSegment{partial("text"), final("text")},
+		},
+		{
+			name:   "colors are dropped",
+			chunks: []string{"\x1b[38;5;160mred\x1b[0m text\n"},
+			want:   []StreamSegment{final("red text")},
+		},
+		{
+			name:   "banners and markers",
+			chunks: []string{"[Start speaking]\n\x1b[2K\r [BLANK_AUDIO]\n (music)\n *laughs*\nok [inaudible] then\n"},
+			want:   []StreamSegment{final("ok then")},
+		},
+		{
+			name:   "partial that becomes a marker is retracted",
+			chunks: []string{"\x1b[2K\r Uh", "\x1b[2K\r [BLANK_AUDIO]\r\n"},
+			want:   []StreamSegment{partial("Uh"), final("")},
+		},
+		{
+			name:   "logs",
+			chunks: []string{"whisper_model_load: n_vocab = 51864\nmain: processing 48000 samples\n### Transcription 1 END\n"},
+			want:   nil,
+		},
+		{
+			name:   "timestamps",
+			chunks: []string{"[00:00:00.000 --> 00:00:02.400]   Why did it roll back?\n"},
+			want:   []StreamSegment{final("Why did it roll back?")},
+		},
+		{
+			name:   "flush finishes the line",
+			chunks: []string{"\x1b[2K\r unfinished"},
+			want:   []StreamSegment{partial("unfinished"), final("unfinished")},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := parseAll(tt.chunks...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStreamParserCaptures(t *testing.T) {
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{"stream_step.txt", []string{
+			"So the deploy failed last night.",
+			"Can someone check the payments queue? It's backing up.",
+			"What's the p99 latency",
+		}},
+		{"stream_vad.txt", []string{
+			"Why did the canary roll back?",
+			"The error rate went above",
+			"two percent.",
+		}},
+	}
+
+	for _, tt := range tests {
+		data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", tt.file, err)
+		}
+
+		if got := finals(parseAll(string(data))); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got finals %q, want %q", tt.file, got, tt.want)
+		}
+		if segments := parseAll(string(data)); unresolved(segments) {
+			t.Errorf("%s: a partial was left without a final: %+v", tt.file, segments)
+		}
+
+		// Reads from the pty can end anywhere
+		bytes := strings.Split(string(data), "")
+		if got := finals(parseAll(bytes...)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s byte by byte: got finals %q, want %q", tt.file, got, tt.want)
+		}
+	}
+}
+
+func FuzzStreamParser(f *testing.F) {
+	for _, file := range []string{"stream_step.txt", "stream_vad.txt"} {
+		data, err := os.ReadFile(filepath.Join("testdata", file))
+		if err != nil {
+			f.Fatalf("Failed to read %s: %v", file, err)
+		}
+		f.Add(data, len(data)/3)
+	}
+	f.Add([]byte("a\x1b[2K\rb\r\n\x1b"), 3)
+
+	f.Fuzz(func(t *testing.T, data []byte, split int) {
+		if split < 0 || split > len(data) {
+			split = len(data) / 2
+		}
+
+		whole := parseAll(string(data))
+		if unresolved(whole) {
+			t.Errorf("A partial was left without a final: %+v", whole)
+		}
+		for _, segment := range whole {
+			if (segment.Text == "" && !segment.Final) || segment.Text != strings.TrimSpace(segment.Text) {
+				t.Errorf("Segment text not trimmed: %q", segment.Text)
+			}
+			if strings.ContainsFunc(segment.Text, func(r rune) bool { return r < 0x20 || r == 0x7f }) {
+				t.Errorf("Segment text has control characters: %q", segment.Text)
+			}
+		}
+
+		// Final segments do not depend on where reads end
+		parts := parseAll(string(data[:split]), string(data[split:]))
+		if got, want := finals(parts), finals(whole); !reflect.DeepEqual(got, want) {
+			t.Errorf("Split at %d: got finals %q, want %q", split, got, want)
+		}
+	})
+}
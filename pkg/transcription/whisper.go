@@ -1,9 +1,10 @@
 package transcription
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os/exec"
 	"strconv"
 	"sync"
@@ -15,24 +16,27 @@ import (
 
 // WhisperHandler manages the whisper.cpp transcription process
 type WhisperHandler struct {
-	whisperPath   string
-	modelPath     string
-	bufferTimeout time.Duration
-	cmd           *exec.Cmd
-	appState      *state.AppState
+	whisperPath string
+	modelPath   string
+	device      Device
+	cmd         *exec.Cmd
+	appState    *state.AppState
 
 	mu        sync.Mutex
 	cancel    context.CancelFunc
 	isRunning bool
+
+	// lineStart is when whisper started the line it is revising
+	lineStart time.Time
 }
 
-// NewWhisperHandler creates a new transcription handler
-func NewWhisperHandler(whisperPath string, modelPath string, bufferTimeout float64, appState *state.AppState) *WhisperHandler {
+// NewWhisperHandler creates a new transcription handler capturing from the device
+func NewWhisperHandler(whisperPath string, modelPath string, device Device, appState *state.AppState) *WhisperHandler {
 	return &WhisperHandler{
-		whisperPath:   whisperPath,
-		modelPath:     modelPath,
-		bufferTimeout: time.Duration(bufferTimeout * float64(time.Second)),
-		appState:      appState,
+		whisperPath: whisperPath,
+		modelPath:   modelPath,
+		device:      device,
+		appState:    appState,
 	}
 }
 
@@ -46,13 +50,11 @@ func (h *WhisperHandler) Start(ctx context.Context) error {
 	h.isRunning = true
 	h.mu.Unlock()
 
-	deviceID := GetDeviceIDByName("VB-Cable")
-
 	ctx, h.cancel = context.WithCancel(ctx)
 	h.cmd = exec.CommandContext(ctx, h.whisperPath,
 		// "-t", "8",
 		"-m", h.modelPath,
-		"-c", strconv.Itoa(deviceID),
+		"-c", strconv.Itoa(h.device.Index),
 	)
 
 	ptmx, err := pty.Start(h.cmd)
@@ -73,37 +75,20 @@ func (h *WhisperHandler) Start(ctx context.Context) error {
 	// 	return err
 	// }
 
-	// Goroutine to read stdout and update app state
+	// Goroutine to parse the terminal output into transcript segments
 	go func() {
-		scanner := bufio.NewScanner(ptmx)
-		for scanner.Scan() {
-			line := scanner.Text()
-
-			// // Handle clear-line + carriage return (\x1b[2K\r)
-			// if strings.Contains(line, "\x1b[2K") {
-			// 	h.appState.TranscriptState.RemoveLastLine()
-			// 	clean := strings.ReplaceAll(line, "\x1b[2K", "")
-			// 	clean = strings.TrimLeft(clean, "\r")
-			// 	if clean != "" {
-			// 		h.appState.TranscriptState.Write(clean)
-			// 	}
-			// 	continue
-			// }
-
-			// // If the line starts with a carriage return, treat as overwrite (delete previous line)
-			// if strings.HasPrefix(line, "\r") {
-			// 	h.appState.TranscriptState.RemoveLastLine()
-			// 	clean := strings.TrimLeft(line, "\r")
-			// 	if clean != "" {
-			// 		h.appState.TranscriptState.Write(clean)
-			// 	}
-			// 	continue
-			// }
-
-			h.appState.TranscriptState.Write(line)
-		}
-		if err := scanner.Err(); err != nil {
-			fmt.Printf("Error reading stdout: %v\n", err)
+		parser := NewStreamParser()
+		buf := make([]byte, 4096)
+		for {
+			n, err := ptmx.Read(buf)
+			h.write(parser.Feed(buf[:n]))
+			if err != nil {
+				h.write(parser.Flush())
+				if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+					fmt.Printf("Error reading stdout: %v\n", err)
+				}
+				return
+			}
 		}
 	}()
 
@@ -122,6 +107,27 @@ func (h *WhisperHandler) Start(ctx context.Context) error {
 	return nil
 }
 
+// write adds the segments to the transcript, timed from when whisper started the line
+func (h *WhisperHandler) write(segments []StreamSegment) {
+	for _, segment := range segments {
+		now := time.Now()
+		if h.lineStart.IsZero() {
+			h.lineStart = now
+		}
+
+		h.appState.TranscriptState.Add(state.Segment{
+			End:    now.Sub(h.lineStart).Seconds(),
+			Time:   h.lineStart,
+			Source: h.device.Source(),
+			Text:   segment.Text,
+			Final:  segment.Final,
+		})
+		if segment.Final {
+			h.lineStart = time.Time{}
+		}
+	}
+}
+
 // Stop terminates the transcription process
 func (h *WhisperHandler) Stop() error {
 	h.mu.Lock()
@@ -0,0 +1,304 @@
+package transcription
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dimitarkovachev/eng-assist/pkg/state"
+)
+
+const (
+	// quietWindow is how far back from the end of a chunk the pause to cut it at is looked for
+	quietWindow = time.Second
+
+	// frameSamples is the length of the frames compared when looking for a pause, 20ms
+	frameSamples = SampleRate / 50
+
+	// maxQueuedChunks bounds the chunks waiting for the server before reading blocks
+	maxQueuedChunks = 8
+)
+
+// WhisperServer transcribes captured audio in chunks with a whisper.cpp server.
+// Chunks end at a pause close to the chunk length, so words are not cut at their edges.
+type WhisperServer struct {
+	url      string
+	chunk    time.Duration
+	source   state.Source
+	open     func() (io.ReadCloser, error)
+	client   *http.Client
+	appState *state.AppState
+
+	mu        sync.Mutex
+	cancel    context.CancelFunc
+	audio     io.ReadCloser
+	isRunning bool
+}
+
+// NewWhisperServer creates a transcriptor posting chunks of audio from open to the server at url.
+// open returns mono 16-bit little-endian PCM at SampleRate, as Capture does.
+func NewWhisperServer(url string, chunk time.Duration, source state.Source, open func() (io.ReadCloser, error), appState *state.AppState) *WhisperServer {
+	return &WhisperServer{
+		url:      strings.TrimRight(url, "/"),
+		chunk:    chunk,
+		source:   source,
+		open:     open,
+		client:   &http.Client{Timeout: time.Minute},
+		appState: appState,
+	}
+}
+
+// Start opens the audio source and transcribes it until the context ends or Stop is called
+func (s *WhisperServer) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isRunning {
+		return nil
+	}
+
+	audio, err := s.open()
+	if err != nil {
+		return err
+	}
+	s.audio = audio
+	s.isRunning = true
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	go s.run(ctx, audio)
+
+	return nil
+}
+
+// Stop ends transcription and closes the audio source
+func (s *WhisperServer) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.isRunning {
+		return nil
+	}
+
+	s.cancel()
+	s.isRunning = false
+	return s.audio.Close()
+}
+
+func (s *WhisperServer) run(ctx context.Context, audio io.ReadCloser) {
+	defer audio.Close()
+
+	// Chunks are transcribed in order while reading goes on, so slow requests never hold up capture
+	chunks := make(chan audioChunk, maxQueuedChunks)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for chunk := range chunks {
+			s.transcribeChunk(ctx, chunk.pcm, chunk.captured)
+		}
+	}()
+	defer func() {
+		close(chunks)
+		<-done
+	}()
+
+	// Capture times follow from the samples read since the start, however long requests take
+	started := time.Now()
+	sent := 0
+	send := func(pcm []byte) bool {
+		chunk := audioChunk{
+			pcm:      append([]byte(nil), pcm...),
+			captured: started.Add(time.Duration(float64(sent/2) / SampleRate * float64(time.Second))),
+		}
+		sent += len(pcm)
+		select {
+		case chunks <- chunk:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	// Each sample is 2 bytes
+	chunkBytes := 2 * int(s.chunk.Seconds()*SampleRate)
+	windowBytes := 2 * int(min(quietWindow, s.chunk/2).Seconds()*SampleRate)
+	buf := make([]byte, chunkBytes)
+	var pending []byte
+	for {
+		n, err := audio.Read(buf)
+		if ctx.Err() != nil {
+			return
+		}
+		pending = append(pending, buf[:n]...)
+		for len(pending) >= chunkBytes {
+			cut := quietCut(pending[:chunkBytes], windowBytes)
+			if !send(pending[:cut]) {
+				return
+			}
+			pending = pending[cut:]
+		}
+
+		if errors.Is(err, io.EOF) {
+			if len(pending) > 0 {
+				send(pending)
+			}
+			return
+		}
+		if err != nil {
+			fmt.Printf("Error reading audio: %v\n", err)
+			return
+		}
+	}
+}
+
+// audioChunk is a piece of audio and the time its first sample was captured
+type audioChunk struct {
+	pcm      []byte
+	captured time.Time
+}
+
+// quietCut returns where a chunk of pcm should end: after the quietest frame in its last window bytes,
+// so a word is not split across two requests. Ties go to the later frame.
+func quietCut(pcm []byte, window int) int {
+	frame := 2 * frameSamples
+	cut, quietest := len(pcm), int64(-1)
+	for end := len(pcm); end-frame >= 0 && end-frame >= len(pcm)-window; end -= frame {
+		var energy int64
+		for i := end - frame; i+1 < end; i += 2 {
+			sample := int64(int16(binary.LittleEndian.Uint16(pcm[i:])))
+			energy += sample * sample
+		}
+		if quietest < 0 || energy < quietest {
+			cut, quietest = end, energy
+		}
+	}
+	return cut
+}
+
+// transcribeChunk adds the segments of one chunk to the transcript, errors are logged and the chunk dropped
+func (s *WhisperServer) transcribeChunk(ctx context.Context, pcm []byte, captured time.Time) {
+	segments, err := s.Transcribe(ctx, pcm)
+	if err != nil {
+		if ctx.Err() == nil {
+			fmt.Printf("Error transcribing audio: %v\n", err)
+		}
+		return
+	}
+
+	for _, segment := range segments {
+		text := cleanText(segment.Text)
+		if text == "" {
+			continue
+		}
+		s.appState.TranscriptState.Add(state.Segment{
+			Start:  segment.Start,
+			End:    segment.End,
+			Time:   captured.Add(time.Duration(segment.Start * float64(time.Second))),
+			Source: s.source,
+			Text:   text,
+			Final:  true,
+		})
+	}
+}
+
+// ServerSegment is a piece of text the whisper.cpp server recognized, times in seconds into the chunk
+type ServerSegment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// Transcribe posts PCM audio to the server's /inference endpoint and returns the recognized segments
+func (s *WhisperServer) Transcribe(ctx context.Context, pcm []byte) ([]ServerSegment, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("file", "audio.wav")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeWAV(file, pcm); err != nil {
+		return nil, err
+	}
+	form.WriteField("response_format", "verbose_json")
+	form.WriteField("temperature", "0.0")
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url+"/inference", &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach whisper server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Text     string          `json:"text"`
+		Segments []ServerSegment `json:"segments"`
+		Error    string          `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode whisper server response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || result.Error != "" {
+		return nil, fmt.Errorf("whisper server error (status %d): %s", resp.StatusCode, result.Error)
+	}
+
+	// Plain json responses only carry the text
+	if len(result.Segments) == 0 && strings.TrimSpace(result.Text) != "" {
+		return []ServerSegment{{End: float64(len(pcm)/2) / SampleRate, Text: result.Text}}, nil
+	}
+	return result.Segments, nil
+}
+
+// writeWAV writes mono 16-bit PCM at SampleRate as a WAV file
+func writeWAV(w io.Writer, pcm []byte) error {
+	header := struct {
+		RIFF          [4]byte
+		Size          uint32
+		WAVE          [4]byte
+		Fmt           [4]byte
+		FmtSize       uint32
+		AudioFormat   uint16
+		Channels      uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+		Data          [4]byte
+		DataSize      uint32
+	}{
+		RIFF:          [4]byte{'R', 'I', 'F', 'F'},
+		Size:          uint32(36 + len(pcm)),
+		WAVE:          [4]byte{'W', 'A', 'V', 'E'},
+		Fmt:           [4]byte{'f', 'm', 't', ' '},
+		FmtSize:       16,
+		AudioFormat:   1, // PCM
+		Channels:      1,
+		SampleRate:    SampleRate,
+		ByteRate:      SampleRate * 2,
+		BlockAlign:    2,
+		BitsPerSample: 16,
+		Data:          [4]byte{'d', 'a', 't', 'a'},
+		DataSize:      uint32(len(pcm)),
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	_, err := w.Write(pcm)
+	return err
+}
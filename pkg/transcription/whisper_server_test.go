@@ -0,0 +1,144 @@
+package transcription
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dimitarkovachev/eng-assist/pkg/state"
+)
+
+// fakeWhisperServer answers /inference with the next reply, checking the request is a mono 16 kHz WAV
+func fakeWhisperServer(t *testing.T, replies ...string) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/inference" {
+			t.Errorf("Expected /inference, got: %s", r.URL.Path)
+		}
+		if format := r.FormValue("response_format"); format != "verbose_json" {
+			t.Errorf("Expected verbose_json, got: %q", format)
+		}
+
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("Expected an audio file: %v", err)
+			return
+		}
+		wav, _ := io.ReadAll(file)
+		if len(wav) < 44 || string(wav[:4]) != "RIFF" || string(wav[8:12]) != "WAVE" {
+			t.Errorf("Expected a WAV file, got %d bytes", len(wav))
+		} else if rate := binary.LittleEndian.Uint32(wav[24:28]); rate != SampleRate {
+			t.Errorf("Expected %d Hz, got: %d", SampleRate, rate)
+		}
+
+		n := int(calls.Add(1)) - 1
+		if n >= len(replies) {
+			n = len(replies) - 1
+		}
+		if strings.Contains(replies[n], `"error"`) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		w.Write([]byte(replies[n]))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestWhisperServerTranscribe(t *testing.T) {
+	srv, _ := fakeWhisperServer(t,
+		`{"text": " Hello there. How is the deploy?", "segments": [
+			{"start": 0.0, "end": 1.2, "text": " Hello there."},
+			{"start": 1.2, "end": 2.5, "text": " How is the deploy?"}
+		]}`,
+		`{"text": " Plain text only"}`,
+		`{"error": "failed to read WAV file"}`,
+	)
+	server := NewWhisperServer(srv.URL+"/", time.Second, state.SourceMic, nil, state.NewAppState())
+	pcm := make([]byte, 2*SampleRate)
+
+	segments, err := server.Transcribe(context.Background(), pcm)
+	if err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	if len(segments) != 2 || segments[1].Start != 1.2 || segments[1].Text != " How is the deploy?" {
+		t.Errorf("Unexpected segments: %+v", segments)
+	}
+
+	segments, err = server.Transcribe(context.Background(), pcm)
+	if err != nil || len(segments) != 1 || segments[0].End != 1 {
+		t.Errorf("Expected one segment spanning the chunk for a plain response, got: %+v, %v", segments, err)
+	}
+
+	_, err = server.Transcribe(context.Background(), pcm)
+	if err == nil || !strings.Contains(err.Error(), "failed to read WAV file") {
+		t.Errorf("Expected the server error, got: %v", err)
+	}
+}
+
+func TestWhisperServerWritesTranscript(t *testing.T) {
+	srv, calls := fakeWhisperServer(t,
+		`{"segments": [{"start": 0, "end": 1, "text": " [BLANK_AUDIO]"}]}`,
+		`{"segments": [{"start": 0, "end": 1, "text": " Is the queue"}, {"start": 1, "end": 2, "text": " backing up?"}]}`,
+	)
+
+	// Two full chunks and a partial one of 100ms each
+	chunk := 100 * time.Millisecond
+	audio := io.NopCloser(bytes.NewReader(make([]byte, 2*SampleRate/10*2+100)))
+	appState := state.NewAppState()
+	server := NewWhisperServer(srv.URL, chunk, state.SourceLoopback, func() (io.ReadCloser, error) { return audio, nil }, appState)
+
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer server.Stop()
+
+	want := "Is the queue\nbacking up?\nIs the queue\nbacking up?\n"
+	transcript := ""
+	for deadline := time.Now().Add(2 * time.Second); transcript != want && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+		transcript, _ = appState.TranscriptState.GetAll()
+	}
+
+	if transcript != want {
+		t.Errorf("Unexpected transcript: %q", transcript)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected a request per chunk including the partial one, got: %d", calls.Load())
+	}
+
+	segments := appState.TranscriptState.Segments()
+	if len(segments) != 4 {
+		t.Fatalf("Expected a segment per spoken server segment, got: %+v", segments)
+	}
+	if got := segments[1]; got.Source != state.SourceLoopback || !got.Final || math.Abs(got.End-got.Start-1) > 1e-9 || !got.Time.Equal(segments[0].Time.Add(time.Second)) {
+		t.Errorf("Expected a timed loopback segment a second into the chunk, got: %+v", got)
+	}
+	if got := segments[2].Time.Sub(segments[0].Time); got != chunk {
+		t.Errorf("Expected the second chunk to start a chunk of samples after the first, got: %v", got)
+	}
+}
+
+func TestQuietCut(t *testing.T) {
+	// A second of loud audio with a 20ms pause ending at 0.72s
+	pcm := make([]byte, 2*SampleRate)
+	for i := 0; i < len(pcm); i += 2 {
+		binary.LittleEndian.PutUint16(pcm[i:], 3000)
+	}
+	pause := 2 * SampleRate * 72 / 100
+	clear(pcm[pause-2*frameSamples : pause])
+
+	if cut := quietCut(pcm, len(pcm)/2); cut != pause {
+		t.Errorf("Expected a cut at the pause, byte %d, got: %d", pause, cut)
+	}
+	if cut := quietCut(pcm, len(pcm)/4); cut != len(pcm) {
+		t.Errorf("Expected a cut at the end without a pause in the window, got: %d", cut)
+	}
+}
@@ -0,0 +1,47 @@
+package trigger
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// technicalRe matches engineering vocabulary that benefits from a stronger model
+	technicalRe = regexp.MustCompile(`(?i)\b(stack ?trace|exception|panic|segfault|deadlock|race condition|memory leak|latency|throughput|` +
+		`concurren\w*|mutex|goroutines?|threads?|database|schema|migrations?|index(es)?|query plan|sql|transactions?|` +
+		`kubernetes|k8s|terraform|docker|containers?|kafka|redis|postgres\w*|mysql|dns|tls|certificates?|` +
+		`algorithms?|complexity|big o|regex|api|grpc|protocol|encryption|oauth|authentication|` +
+		`architecture|scal(e|ing|ability)|sharding|replication|consisten(t|cy)|cach(e|ing)|trade-?offs?|refactor\w*|` +
+		`compiler|runtime|garbage collect\w*|kernel|profil(e|ing|er)|benchmarks?)\b`)
+
+	// deepRe matches questions asking for reasoning rather than a fact
+	deepRe = regexp.MustCompile(`(?i)\b(why (does|do|is|would|did)|how would (we|you)|what would happen|root cause|compare|pros and cons|design (a|the)|walk (me|us) through)\b`)
+
+	// sourceRe matches function calls and source file names, which are about code on their own
+	sourceRe = regexp.MustCompile(`\b[A-Za-z_]\w*\(\)|\b[a-z]+[A-Z]\w*\(|\b\w+\.(go|py|js|ts|java|rs|sql|yaml|yml|json)\b`)
+
+	// codeRe matches snake_case identifiers read out or pasted into the transcript
+	codeRe = regexp.MustCompile(`\b\w+_\w+\b`)
+)
+
+// Technical reports whether the text asks a technical or deep question.
+// It needs a function call or source file name, two technical terms, or one with a deep
+// question or an identifier, so small talk mentioning a single term stays with the small model.
+func Technical(text string) bool {
+	if sourceRe.MatchString(text) {
+		return true
+	}
+
+	terms := len(technicalRe.FindAllString(text, 3))
+	deep := deepRe.MatchString(text)
+	code := codeRe.MatchString(text)
+
+	switch {
+	case terms >= 2:
+		return true
+	case terms == 1:
+		return deep || code
+	default:
+		return deep && strings.Contains(text, "?")
+	}
+}
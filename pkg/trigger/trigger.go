@@ -0,0 +1,100 @@
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dimitarkovachev/eng-assist/pkg/ai"
+)
+
+// Decision is the outcome of a trigger for a transcript chunk
+type Decision struct {
+	Answer bool
+	Reason string
+
+	// Response is the classifier's own model response, if any, so its cost can be recorded
+	Response *ai.Response
+}
+
+// Trigger decides whether a transcript chunk needs an AI answer
+type Trigger interface {
+	Decide(ctx context.Context, chunk string) (Decision, error)
+}
+
+// Always answers every chunk
+type Always struct{}
+
+// Decide always asks for an answer
+func (Always) Decide(ctx context.Context, chunk string) (Decision, error) {
+	return Decision{Answer: true, Reason: "always"}, nil
+}
+
+var (
+	// interrogatives that open a question at the start of a sentence. Auxiliary verbs such as
+	// "do", "is" or "have" also open statements ("Do it now."), so they only count with a question mark.
+	interrogativeRe = regexp.MustCompile(`(?i)(^|[.!?]\s+)(what|why|how|when|where|who|whom|whose|which|could|should|would|shall)\b`)
+
+	// phrases that ask for input anywhere in a sentence
+	phraseRe = regexp.MustCompile(`(?i)\b(what about|how about|how do (we|i|you)|how can (we|i)|any (idea|ideas|thoughts)|does anyone know|do you know|can you|could you|i wonder|not sure (how|why|what|whether|if))\b`)
+)
+
+// Heuristic detects questions from punctuation, interrogatives and common asking phrases
+type Heuristic struct{}
+
+// NewHeuristic creates the default heuristic trigger
+func NewHeuristic() *Heuristic {
+	return &Heuristic{}
+}
+
+// Decide answers chunks that look like a question or a request for help
+func (h *Heuristic) Decide(ctx context.Context, chunk string) (Decision, error) {
+	text := strings.TrimSpace(chunk)
+
+	if strings.Contains(text, "?") {
+		return Decision{Answer: true, Reason: "question mark"}, nil
+	}
+	if match := phraseRe.FindString(text); match != "" {
+		return Decision{Answer: true, Reason: fmt.Sprintf("phrase %q", strings.ToLower(match))}, nil
+	}
+	if match := interrogativeRe.FindStringSubmatch(text); match != nil {
+		return Decision{Answer: true, Reason: fmt.Sprintf("interrogative %q", strings.ToLower(match[2]))}, nil
+	}
+
+	return Decision{Answer: false, Reason: "no question detected"}, nil
+}
+
+const classifierPrompt = "You decide whether a snippet of a live engineering conversation contains a question, " +
+	"a problem or a request that an assistant should answer. Reply with YES or NO followed by a short reason."
+
+// LLM asks a model to classify the chunk, which catches questions the heuristic misses
+type LLM struct {
+	tool ai.Tool
+}
+
+// NewLLM creates a trigger that classifies chunks with the tool
+func NewLLM(tool ai.Tool) *LLM {
+	return &LLM{
+		tool: tool,
+	}
+}
+
+// Decide answers chunks the model classifies as needing an answer
+func (l *LLM) Decide(ctx context.Context, chunk string) (Decision, error) {
+	resp, err := l.tool.Complete(ctx, ai.Request{
+		System:    classifierPrompt,
+		Messages:  []ai.Message{{Role: "user", Content: chunk}},
+		MaxTokens: 32,
+	})
+	if err != nil {
+		return Decision{}, fmt.Errorf("failed to classify chunk: %w", err)
+	}
+
+	reply := strings.TrimSpace(resp.Text)
+	return Decision{
+		Answer:   strings.HasPrefix(strings.ToUpper(reply), "YES"),
+		Reason:   "llm: " + reply,
+		Response: resp,
+	}, nil
+}
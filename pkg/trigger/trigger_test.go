@@ -0,0 +1,106 @@
+package trigger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dimitarkovachev/eng-assist/pkg/ai"
+)
+
+func TestHeuristic(t *testing.T) {
+	tests := []struct {
+		chunk  string
+		answer bool
+	}{
+		{"How do we roll back the deploy", true},
+		{"so the cache is warm, what about the eviction policy", true},
+		{"is the migration idempotent?", true},
+		{"we shipped it yesterday. Should we tag a release", true},
+		{"anyone know the on-call rotation?", true},
+		{"can you remind me what the timeout is", true},
+		{"I wonder if the queue is backing up", true},
+		{"okay let's move on to the next item", false},
+		{"the deploy finished and everything is green", false},
+		{"Have a good one.", false},
+		{"Do it now.", false},
+		{"let me explain the rollout plan", false},
+		{"we finally figured out what's the bottleneck", false},
+		{"Will do, thanks", false},
+		{"", false},
+	}
+
+	h := NewHeuristic()
+	for _, tt := range tests {
+		decision, err := h.Decide(context.Background(), tt.chunk)
+		if err != nil {
+			t.Fatalf("Decide(%q) error: %v", tt.chunk, err)
+		}
+		if decision.Answer != tt.answer {
+			t.Errorf("Decide(%q) = %v (%s), expected %v", tt.chunk, decision.Answer, decision.Reason, tt.answer)
+		}
+		if decision.Reason == "" {
+			t.Errorf("Decide(%q) returned no reason", tt.chunk)
+		}
+	}
+}
+
+type stubTool struct {
+	ai.Tool
+	text string
+}
+
+func (s *stubTool) Complete(ctx context.Context, req ai.Request) (*ai.Response, error) {
+	return &ai.Response{Text: s.text, Model: "stub", Cost: 0.001}, nil
+}
+
+func TestLLM(t *testing.T) {
+	tests := []struct {
+		reply  string
+		answer bool
+	}{
+		{"YES - asks about deployment", true},
+		{"yes", true},
+		{"NO, just status", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		l := NewLLM(&stubTool{text: tt.reply})
+		decision, err := l.Decide(context.Background(), "chunk")
+		if err != nil {
+			t.Fatalf("Decide() error: %v", err)
+		}
+		if decision.Answer != tt.answer {
+			t.Errorf("reply %q: expected answer %v, got %v", tt.reply, tt.answer, decision.Answer)
+		}
+		if decision.Response == nil || decision.Response.Cost != 0.001 {
+			t.Errorf("reply %q: expected classifier response to be returned", tt.reply)
+		}
+	}
+}
+
+func TestTechnical(t *testing.T) {
+	tests := []struct {
+		text      string
+		technical bool
+	}{
+		{"why does the postgres replication lag under load?", true},
+		{"we hit a deadlock in the database migration", true},
+		{"what does handleReset() do in ui.go", true},
+		{"who last touched config.yaml?", true},
+		{"does the retry_count matter", false},
+		{"what is the retry_count in the api", true},
+		{"is the api down? check handleReset() in main.go", true},
+		{"why would we pick kafka here?", true},
+		{"what time is lunch?", false},
+		{"who is taking notes today?", false},
+		{"can we cache that for the demo", false},
+		{"why did the build break?", true},
+	}
+
+	for _, tt := range tests {
+		if got := Technical(tt.text); got != tt.technical {
+			t.Errorf("Technical(%q) = %v, want %v", tt.text, got, tt.technical)
+		}
+	}
+}
@@ -2,35 +2,70 @@ package ui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"sync"
 
+	"github.com/dimitarkovachev/eng-assist/pkg/extract"
+	"github.com/dimitarkovachev/eng-assist/pkg/prompt"
 	"github.com/dimitarkovachev/eng-assist/pkg/state"
+	"github.com/dimitarkovachev/eng-assist/pkg/summary"
+	"github.com/dimitarkovachev/eng-assist/pkg/transcription"
 	"github.com/gin-gonic/gin"
 )
 
 // AssistantUI manages the web interface
 type AssistantUI struct {
-	router    *gin.Engine
-	onPause   func()
-	mu        sync.RWMutex
-	isRunning bool
-	appState  *state.AppState
+	router     *gin.Engine
+	onPause    func()
+	onReset    func()
+	onSummary  func(ctx context.Context) (*summary.Report, error)
+	onEscalate func() error
+	devices    transcription.DeviceLister
+	mu         sync.RWMutex
+	isRunning  bool
+	appState   *state.AppState
+	personas   *prompt.Library
+	extractor  *extract.Extractor
 }
 
 // State represents the current UI state
 type State struct {
 	Transcript string  `json:"transcript"`
+	Partial    string  `json:"partial,omitempty"`
 	Response   string  `json:"response"`
 	Cost       float64 `json:"cost"`
+	Responding bool    `json:"responding"`
+	Queued     int     `json:"queued"`
+
+	BudgetExhausted bool   `json:"budget_exhausted"`
+	Error           string `json:"error,omitempty"`
+	AudioError      string `json:"audio_error,omitempty"`
 }
 
 // NewAssistantUI creates a new UI instance
-func NewAssistantUI(onPause func(), appState *state.AppState) *AssistantUI {
+// personas may be nil when no persona library is loaded.
+// onReset runs before the session state is cleared, so it can still read the transcript.
+func NewAssistantUI(
+	onPause func(),
+	onReset func(),
+	onSummary func(ctx context.Context) (*summary.Report, error),
+	onEscalate func() error,
+	devices transcription.DeviceLister,
+	appState *state.AppState,
+	personas *prompt.Library,
+	extractor *extract.Extractor,
+) *AssistantUI {
 	ui := &AssistantUI{
-		onPause:  onPause,
-		appState: appState,
+		onPause:    onPause,
+		onReset:    onReset,
+		onSummary:  onSummary,
+		onEscalate: onEscalate,
+		devices:    devices,
+		appState:   appState,
+		personas:   personas,
+		extractor:  extractor,
 	}
 
 	// Setup Gin router
@@ -40,8 +75,20 @@ func NewAssistantUI(onPause func(), appState *state.AppState) *AssistantUI {
 	api := router.Group("/api")
 	{
 		api.GET("/state", ui.getState)
+		api.GET("/costs", ui.getCosts)
 		api.POST("/reset", ui.handleReset)
 		api.POST("/pause", ui.handlePause)
+		api.GET("/personas", ui.getPersonas)
+		api.POST("/persona", ui.handlePersona)
+		api.POST("/notes", ui.handleNotes)
+		api.GET("/actions", ui.getActions)
+		api.GET("/decisions", ui.getDecisions)
+		api.POST("/extract", ui.handleExtract)
+		api.POST("/summary", ui.handleSummary)
+		api.POST("/escalate", ui.handleEscalate)
+		api.GET("/devices", ui.getDevices)
+		api.GET("/transcript", ui.getTranscript)
+		api.POST("/transcript", ui.handleTranscript)
 	}
 
 	// Serve static files
@@ -104,12 +151,29 @@ func (ui *AssistantUI) getState(c *gin.Context) {
 
 	c.JSON(http.StatusOK, State{
 		Transcript: ts,
+		Partial:    ui.appState.TranscriptState.Partial(),
 		Response:   ars,
-		Cost:       0,
+		Cost:       ui.appState.GetCost(),
+		Responding: ui.appState.IsResponding(),
+		Queued:     ui.appState.Queued(),
+
+		BudgetExhausted: ui.appState.IsBudgetExhausted(),
+		Error:           ui.appState.GetAIError(),
+		AudioError:      ui.appState.GetAudioError(),
+	})
+}
+
+func (ui *AssistantUI) getCosts(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"total":    ui.appState.GetCost(),
+		"requests": ui.appState.Ledger(),
 	})
 }
 
 func (ui *AssistantUI) handleReset(c *gin.Context) {
+	if ui.onReset != nil {
+		ui.onReset()
+	}
 	ui.appState.Clear()
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
@@ -121,3 +185,138 @@ func (ui *AssistantUI) handlePause(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
+
+func (ui *AssistantUI) getPersonas(c *gin.Context) {
+	if ui.personas == nil {
+		c.JSON(http.StatusOK, gin.H{"personas": []string{}})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"personas": ui.personas.Names(),
+		"active":   ui.personas.Active(),
+		"notes":    ui.personas.Notes(),
+	})
+}
+
+func (ui *AssistantUI) handlePersona(c *gin.Context) {
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ui.personas == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no personas loaded"})
+		return
+	}
+
+	if err := ui.personas.SetActive(req.Name); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func (ui *AssistantUI) handleNotes(c *gin.Context) {
+	var req struct {
+		Notes string `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ui.personas == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no personas loaded"})
+		return
+	}
+
+	ui.personas.SetNotes(req.Notes)
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func (ui *AssistantUI) getActions(c *gin.Context) {
+	result := ui.extractor.Store().Result()
+	c.JSON(http.StatusOK, gin.H{"action_items": result.ActionItems})
+}
+
+func (ui *AssistantUI) getDecisions(c *gin.Context) {
+	result := ui.extractor.Store().Result()
+	c.JSON(http.StatusOK, gin.H{
+		"decisions":      result.Decisions,
+		"open_questions": result.OpenQuestions,
+	})
+}
+
+func (ui *AssistantUI) handleExtract(c *gin.Context) {
+	if _, err := ui.extractor.Extract(c.Request.Context()); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, extract.ErrNoTranscript) {
+			status = http.StatusBadRequest
+		}
+		if errors.Is(err, extract.ErrReset) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ui.extractor.Store().Result())
+}
+
+func (ui *AssistantUI) handleSummary(c *gin.Context) {
+	report, err := ui.onSummary(c.Request.Context())
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, summary.ErrNoTranscript) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// handleEscalate asks the last answered transcript again of the large model
+func (ui *AssistantUI) handleEscalate(c *gin.Context) {
+	if err := ui.onEscalate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "escalated"})
+}
+
+// getDevices lists the audio capture devices AUDIO_DEVICE can select
+func (ui *AssistantUI) getDevices(c *gin.Context) {
+	devices, err := ui.devices.CaptureDevices()
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, transcription.ErrNoAudio) {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"devices": devices})
+}
+
+// getTranscript returns the transcript segments with their timing
+func (ui *AssistantUI) getTranscript(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"segments": ui.appState.TranscriptState.Segments()})
+}
+
+// handleTranscript adds typed text to the transcript, as if it had been spoken
+func (ui *AssistantUI) handleTranscript(c *gin.Context) {
+	var req struct {
+		Text string `json:"text" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ui.appState.TranscriptState.Add(state.Segment{Source: state.SourceTyped, Text: req.Text, Final: true})
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
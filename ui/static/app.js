@@ -4,7 +4,19 @@ let isPaused = false;
 // DOM elements
 const transcriptElement = document.getElementById('transcript');
 const responseElement = document.getElementById('response');
+const responsePanel = document.getElementById('response-panel');
 const costElement = document.querySelector('.cost');
+const errorElement = document.querySelector('.error');
+const queuedElement = document.querySelector('.queued');
+const audioErrorElement = document.querySelector('.audio-error');
+const sayElement = document.getElementById('say');
+const personaElement = document.getElementById('persona');
+const notesElement = document.getElementById('notes');
+const actionsElement = document.getElementById('actions');
+const decisionsElement = document.getElementById('decisions');
+const questionsElement = document.getElementById('questions');
+const summaryElement = document.getElementById('summary');
+const tldrElement = document.getElementById('tldr');
 
 // Keyboard shortcuts
 document.addEventListener('keydown', (e) => {
@@ -18,6 +30,14 @@ document.addEventListener('keydown', (e) => {
                 e.preventDefault();
                 togglePause();
                 break;
+            case 'e':
+                e.preventDefault();
+                extractRecords();
+                break;
+            case 'b':
+                e.preventDefault();
+                askBigModel();
+                break;
             case 'q':
                 e.preventDefault();
                 // Quit functionality can be handled by closing the tab
@@ -32,6 +52,8 @@ function resetAssistant() {
         .then(() => {
             transcriptElement.textContent = '';
             responseElement.textContent = '';
+            summaryElement.hidden = true;
+            updateRecords();
         })
         .catch(console.error);
 }
@@ -42,6 +64,113 @@ function togglePause() {
         .catch(console.error);
 }
 
+function setPersona(name) {
+    fetch('/api/persona', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ name }),
+    }).catch(console.error);
+}
+
+function setNotes(notes) {
+    fetch('/api/notes', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ notes }),
+    }).catch(console.error);
+}
+
+function loadPersonas() {
+    fetch('/api/personas')
+        .then(response => response.json())
+        .then(data => {
+            if (!data.personas || data.personas.length === 0) {
+                return;
+            }
+            personaElement.replaceChildren(...data.personas.map(name => new Option(name, name)));
+            personaElement.value = data.active;
+            notesElement.value = data.notes || '';
+            personaElement.hidden = false;
+            notesElement.hidden = false;
+        })
+        .catch(console.error);
+}
+
+// say adds typed text to the transcript, the only input when no audio is captured
+function say(text) {
+    if (!text.trim()) {
+        return;
+    }
+    fetch('/api/transcript', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ text }),
+    }).catch(console.error);
+}
+
+sayElement.addEventListener('keydown', (e) => {
+    if (e.key === 'Enter') {
+        say(sayElement.value);
+        sayElement.value = '';
+    }
+});
+
+function extractRecords() {
+    fetch('/api/extract', { method: 'POST' })
+        .then(updateRecords)
+        .catch(console.error);
+}
+
+function askBigModel() {
+    fetch('/api/escalate', { method: 'POST' })
+        .catch(console.error);
+}
+
+function summarizeSession() {
+    tldrElement.textContent = 'Summarizing...';
+    summaryElement.hidden = false;
+    fetch('/api/summary', { method: 'POST' })
+        .then(response => response.json())
+        .then(data => {
+            tldrElement.textContent = data.error ? `Summary failed: ${data.error}` : data.tldr;
+        })
+        .catch(console.error);
+}
+
+// listItems renders one list item per record, with optional details after the text
+function listItems(element, records, text, details) {
+    element.replaceChildren(...records.map(record => {
+        const item = document.createElement('li');
+        item.textContent = text(record);
+        const meta = details ? details(record) : '';
+        if (meta) {
+            const span = document.createElement('span');
+            span.className = 'meta';
+            span.textContent = ` (${meta})`;
+            item.appendChild(span);
+        }
+        return item;
+    }));
+}
+
+function updateRecords() {
+    fetch('/api/actions')
+        .then(response => response.json())
+        .then(data => {
+            listItems(actionsElement, data.action_items || [], item => item.task,
+                item => [item.owner, item.due && `due ${item.due}`].filter(Boolean).join(', '));
+        })
+        .catch(console.error);
+
+    fetch('/api/decisions')
+        .then(response => response.json())
+        .then(data => {
+            listItems(decisionsElement, data.decisions || [], decision => decision.decision);
+            listItems(questionsElement, data.open_questions || [], question => question);
+        })
+        .catch(console.error);
+}
+
 // State polling
 function updateState() {
     fetch('/api/state')
@@ -49,16 +178,39 @@ function updateState() {
         .then(data => {
             if (data.transcript !== undefined) {
                 transcriptElement.textContent = data.transcript;
+                // Text whisper is still revising is shown after the final text
+                if (data.partial) {
+                    const partial = document.createElement('span');
+                    partial.className = 'partial';
+                    partial.textContent = data.partial;
+                    transcriptElement.appendChild(partial);
+                }
             }
             if (data.response !== undefined) {
                 responseElement.textContent = data.response;
             }
+            if (data.responding !== undefined) {
+                responsePanel.classList.toggle('responding', data.responding);
+            }
+            errorElement.textContent = data.error || '';
+            errorElement.hidden = !data.error;
+            audioErrorElement.textContent = data.audio_error ? `No audio, type below instead: ${data.audio_error}` : '';
+            audioErrorElement.hidden = !data.audio_error;
+            queuedElement.textContent = `${data.queued} queued`;
+            queuedElement.hidden = !data.queued;
             if (data.cost !== undefined) {
                 costElement.textContent = `Cost: $${data.cost.toFixed(4)}`;
+                if (data.budget_exhausted) {
+                    costElement.textContent += ' (budget exhausted, AI paused)';
+                }
+                costElement.classList.toggle('exhausted', !!data.budget_exhausted);
             }
         })
         .catch(console.error);
 }
 
 // Start polling
-setInterval(updateState, 250); 
\ No newline at end of file
+loadPersonas();
+updateRecords();
+setInterval(updateState, 250);
+setInterval(updateRecords, 5000); 
\ No newline at end of file